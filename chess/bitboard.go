@@ -0,0 +1,128 @@
+package chess
+
+import "math/bits"
+
+// Bitboard is a set of squares where bit n is set when square n is in the set
+type Bitboard uint64
+
+// SquareBitboard returns a bitboard containing only sq
+func SquareBitboard(sq Square) Bitboard {
+	return Bitboard(1) << uint(sq)
+}
+
+// Has returns true if sq is in the set
+func (b Bitboard) Has(sq Square) bool {
+	return b&SquareBitboard(sq) != 0
+}
+
+// Count returns the number of squares in the set
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// PopLSB removes the lowest square from the set and returns it, the set must not be empty
+func (b *Bitboard) PopLSB() Square {
+	sq := Square(bits.TrailingZeros64(uint64(*b)))
+	*b &= *b - 1
+
+	return sq
+}
+
+// Ray directions, positive directions move towards h8 and negative towards a1
+const (
+	north int = iota
+	northEast
+	east
+	northWest
+	south
+	southWest
+	west
+	southEast
+)
+
+// precomputed attack tables indexed by square
+var (
+	knightAttacks [Size * Size]Bitboard
+	kingAttacks   [Size * Size]Bitboard
+	pawnAttacks   [2][Size * Size]Bitboard
+	rays          [8][Size * Size]Bitboard
+)
+
+// rayOffsets the {file, rank} step of each ray direction
+var rayOffsets = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {-1, 1}, {0, -1}, {-1, -1}, {-1, 0}, {1, -1}}
+
+func init() {
+	for sq := Square(0); int(sq) < Size*Size; sq++ {
+		knightAttacks[sq] = offsetsBitboard(sq, knightOffsets, 1)
+		kingAttacks[sq] = offsetsBitboard(sq, kingOffsets, 1)
+		pawnAttacks[White][sq] = offsetsBitboard(sq, [][2]int{{-1, 1}, {1, 1}}, 1)
+		pawnAttacks[Black][sq] = offsetsBitboard(sq, [][2]int{{-1, -1}, {1, -1}}, 1)
+
+		for direction, offset := range rayOffsets {
+			rays[direction][sq] = offsetsBitboard(sq, [][2]int{offset}, Size)
+		}
+	}
+}
+
+// offsetsBitboard returns the squares reached by stepping from sq along each offset up to stopAfter times
+func offsetsBitboard(sq Square, offsets [][2]int, stopAfter int) Bitboard {
+	var b Bitboard
+	for _, off := range offsets {
+		for i := 1; i <= stopAfter; i++ {
+			f := sq.File() + off[0]*i
+			r := sq.Rank() + off[1]*i
+			if !IsOnBoard(f, r) {
+				break
+			}
+
+			b |= SquareBitboard(NewSquare(f, r))
+		}
+	}
+
+	return b
+}
+
+// rayAttacks returns the squares attacked along direction from sq, stopping at the first piece in occupied
+func rayAttacks(direction int, sq Square, occupied Bitboard) Bitboard {
+	attacks := rays[direction][sq]
+	blockers := uint64(attacks & occupied)
+	if blockers == 0 {
+		return attacks
+	}
+
+	// the nearest blocker is the lowest bit on positive rays and the highest on negative ones
+	var blocker int
+	if direction < south {
+		blocker = bits.TrailingZeros64(blockers)
+	} else {
+		blocker = 63 - bits.LeadingZeros64(blockers)
+	}
+
+	return attacks &^ rays[direction][blocker]
+}
+
+func rookAttacks(sq Square, occupied Bitboard) Bitboard {
+	return rayAttacks(north, sq, occupied) | rayAttacks(east, sq, occupied) | rayAttacks(south, sq, occupied) | rayAttacks(west, sq, occupied)
+}
+
+func bishopAttacks(sq Square, occupied Bitboard) Bitboard {
+	return rayAttacks(northEast, sq, occupied) | rayAttacks(northWest, sq, occupied) | rayAttacks(southEast, sq, occupied) | rayAttacks(southWest, sq, occupied)
+}
+
+// attacksOf returns the squares a piece of pieceType on sq attacks, pawns are not included
+func attacksOf(pieceType PieceType, sq Square, occupied Bitboard) Bitboard {
+	switch pieceType {
+	case King:
+		return kingAttacks[sq]
+	case Queen:
+		return rookAttacks(sq, occupied) | bishopAttacks(sq, occupied)
+	case Rook:
+		return rookAttacks(sq, occupied)
+	case Bishop:
+		return bishopAttacks(sq, occupied)
+	case Knight:
+		return knightAttacks[sq]
+	}
+
+	return 0
+}
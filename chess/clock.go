@@ -0,0 +1,128 @@
+package chess
+
+import "time"
+
+// Clock tracks the time each player has left
+type Clock struct {
+	Control TimeControl
+
+	remaining [2]time.Duration
+
+	// moves each player has made
+	moves [2]int
+
+	// time used on the current move
+	moveElapsed time.Duration
+}
+
+// NewClock returns a clock with both players given the initial time of control
+func NewClock(control TimeControl) *Clock {
+	initial := control.Initial()
+
+	return &Clock{
+		Control:   control,
+		remaining: [2]time.Duration{initial, initial},
+	}
+}
+
+// Remaining returns the time color has left, which is never below zero
+func (c *Clock) Remaining(color Color) time.Duration {
+	if c.remaining[color] < 0 {
+		return 0
+	}
+
+	return c.remaining[color]
+}
+
+// Moves returns the number of moves color has made
+func (c *Clock) Moves(color Color) int {
+	return c.moves[color]
+}
+
+// SetRemaining sets the time color has left and the number of moves they have made
+// and starts a new move
+func (c *Clock) SetRemaining(color Color, remaining time.Duration, moves int) {
+	c.remaining[color] = remaining
+	c.moves[color] = moves
+	c.moveElapsed = 0
+}
+
+// Tick takes elapsed time off the clock of color, who is the player to move
+// with a simple delay the first part of each move is free
+func (c *Clock) Tick(color Color, elapsed time.Duration) {
+	spent := elapsed
+	if c.Control.DelayType == SimpleDelay && c.moveElapsed < c.Control.Delay {
+		free := c.Control.Delay - c.moveElapsed
+		if free > spent {
+			free = spent
+		}
+
+		spent -= free
+	}
+
+	c.moveElapsed += elapsed
+	c.remaining[color] -= spent
+}
+
+// Press ends color's move, adding any increment and Bronstein delay to their clock
+// and the time of the next period if the move ends one
+func (c *Clock) Press(color Color) {
+	if len(c.Control.Periods) == 0 {
+		c.moves[color]++
+		c.moveElapsed = 0
+		return
+	}
+
+	if c.Control.DelayType == BronsteinDelay {
+		refund := c.moveElapsed
+		if refund > c.Control.Delay {
+			refund = c.Control.Delay
+		}
+
+		c.remaining[color] += refund
+	}
+
+	i, last := c.Control.period(c.moves[color])
+	c.remaining[color] += c.Control.Periods[i].Increment
+
+	if last {
+		next := i + 1
+		if next == len(c.Control.Periods) {
+			next = i
+		}
+
+		c.remaining[color] += c.Control.Periods[next].Time
+	}
+
+	c.moves[color]++
+	c.moveElapsed = 0
+}
+
+// TimeLeft returns how long color, who is the player to move, can think before running out of time
+// which includes what is left of a simple delay
+func (c *Clock) TimeLeft(color Color) time.Duration {
+	left := c.Remaining(color)
+	if c.Control.DelayType == SimpleDelay && c.moveElapsed < c.Control.Delay {
+		left += c.Control.Delay - c.moveElapsed
+	}
+
+	return left
+}
+
+// Flagged returns true if color has run out of time
+func (c *Clock) Flagged(color Color) bool {
+	return c.remaining[color] <= 0
+}
+
+// CanCheckmate returns true if color has enough material to possibly checkmate
+// a lone king or a king and a single minor piece is treated as not enough
+func (p *Position) CanCheckmate(color Color) bool {
+	pieces := p.colors[color] &^ p.pieces[color][King]
+	if pieces == 0 {
+		return false
+	}
+
+	minors := p.pieces[color][Knight] | p.pieces[color][Bishop]
+
+	return pieces != minors || minors.Count() > 1
+}
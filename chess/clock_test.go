@@ -0,0 +1,117 @@
+package chess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockIncrement(t *testing.T) {
+	c := NewClock(TimeControl{Periods: []TimePeriod{{Time: time.Minute, Increment: 2 * time.Second}}})
+	c.Tick(White, 5*time.Second)
+	c.Press(White)
+
+	if got, want := c.Remaining(White), 57*time.Second; got != want {
+		t.Errorf("white has %v, want %v", got, want)
+	}
+
+	if got, want := c.Remaining(Black), time.Minute; got != want {
+		t.Errorf("black has %v, want %v", got, want)
+	}
+}
+
+func TestClockSimpleDelay(t *testing.T) {
+	c := NewClock(TimeControl{Periods: []TimePeriod{{Time: time.Minute}}, Delay: 3 * time.Second, DelayType: SimpleDelay})
+	c.Tick(White, 2*time.Second)
+	c.Tick(White, 2*time.Second)
+	c.Press(White)
+
+	if got, want := c.Remaining(White), 59*time.Second; got != want {
+		t.Errorf("white has %v, want %v", got, want)
+	}
+
+	// the delay starts again on the next move
+	c.Tick(Black, 2*time.Second)
+	if got, want := c.Remaining(Black), time.Minute; got != want {
+		t.Errorf("black has %v, want %v", got, want)
+	}
+
+	if got, want := c.TimeLeft(Black), time.Minute+time.Second; got != want {
+		t.Errorf("black can think for %v, want %v", got, want)
+	}
+}
+
+func TestClockBronsteinDelay(t *testing.T) {
+	c := NewClock(TimeControl{Periods: []TimePeriod{{Time: time.Minute}}, Delay: 3 * time.Second, DelayType: BronsteinDelay})
+	c.Tick(White, 2*time.Second)
+	c.Press(White)
+
+	if got, want := c.Remaining(White), time.Minute; got != want {
+		t.Errorf("white has %v, want %v", got, want)
+	}
+
+	c.Tick(Black, 5*time.Second)
+	c.Press(Black)
+
+	if got, want := c.Remaining(Black), 58*time.Second; got != want {
+		t.Errorf("black has %v, want %v", got, want)
+	}
+}
+
+func TestClockPeriods(t *testing.T) {
+	c := NewClock(TimeControl{Periods: []TimePeriod{
+		{Moves: 2, Time: time.Minute},
+		{Moves: 1, Time: 30 * time.Second, Increment: time.Second},
+	}})
+
+	for i := 0; i < 4; i++ {
+		c.Tick(White, time.Second)
+		c.Press(White)
+	}
+
+	// 60s - 4s used + 30s after move 2 + 1s increment on move 3 + 30s after move 3
+	// + 1s increment on move 4 + 30s after move 4 as the last period repeats
+	if got, want := c.Remaining(White), 148*time.Second; got != want {
+		t.Errorf("white has %v, want %v", got, want)
+	}
+
+	if got := c.Moves(White); got != 4 {
+		t.Errorf("white made %v moves, want 4", got)
+	}
+}
+
+func TestClockFlagged(t *testing.T) {
+	c := NewClock(TimeControl{Periods: []TimePeriod{{Time: time.Second}}})
+	c.Tick(White, 2*time.Second)
+
+	if !c.Flagged(White) {
+		t.Error("white should have run out of time")
+	}
+
+	if c.Remaining(White) != 0 {
+		t.Errorf("white has %v, want 0", c.Remaining(White))
+	}
+}
+
+func TestTimeoutStatus(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want Status
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", TimeoutVsInsufficientMaterial},
+		{"4k3/8/8/8/8/8/8/3NK3 b - - 0 1", TimeoutVsInsufficientMaterial},
+		{"4k3/8/8/8/8/8/8/2NNK3 b - - 0 1", Timeout},
+		{"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", Timeout},
+		{"4k3/8/8/8/8/8/8/4KR2 b - - 0 1", Timeout},
+	}
+
+	for _, test := range tests {
+		p, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", test.fen, err)
+		}
+
+		if got := p.TimeoutStatus(p.Turn); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.fen, got, test.want)
+		}
+	}
+}
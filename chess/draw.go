@@ -0,0 +1,52 @@
+package chess
+
+// Number of halfmoves without a capture or pawn move for the move count draw rules
+const (
+	FiftyMoveHalfmoves       int = 100
+	SeventyFiveMoveHalfmoves int = 150
+	ThreefoldRepetitionCount int = 3
+	FivefoldRepetitionCount  int = 5
+)
+
+// canTakeEnPassant returns true if a pawn of the side to move is next to the pawn that can be taken en passant
+func (p *Position) canTakeEnPassant() bool {
+	if p.EnPassant == NoSquare {
+		return false
+	}
+
+	rank := p.EnPassant.Rank() - PawnDirection(p.Turn)
+	for _, file := range []int{p.EnPassant.File() - 1, p.EnPassant.File() + 1} {
+		if IsOnBoard(file, rank) && p.board[NewSquare(file, rank)] == (Piece{Pawn, p.Turn}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsInsufficientMaterial returns true if neither side can checkmate with any sequence of moves
+// that is king against king, king and a minor piece against king or kings and bishops all on the same color squares
+func (p *Position) IsInsufficientMaterial() bool {
+	minors := 0
+	knights := 0
+	bishopSquareColors := [2]int{}
+
+	for sq, piece := range p.board {
+		switch piece.Type {
+		case Pawn, Rook, Queen:
+			return false
+		case Knight:
+			knights++
+			minors++
+		case Bishop:
+			bishopSquareColors[(Square(sq).File()+Square(sq).Rank())%2]++
+			minors++
+		}
+	}
+
+	if minors <= 1 {
+		return true
+	}
+
+	return knights == 0 && (bishopSquareColors[0] == 0 || bishopSquareColors[1] == 0)
+}
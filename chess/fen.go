@@ -0,0 +1,348 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StartingFEN is the FEN of the standard starting position
+const StartingFEN string = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// pieceChars the FEN characters of white's pieces indexed by piece type, black's are lower case
+const pieceChars string = " KQRBNP"
+
+// FEN returns the position as a FEN string
+func (p *Position) FEN() string {
+	var fen strings.Builder
+
+	// piece placement from the eighth rank down
+	for rank := Size - 1; rank >= 0; rank-- {
+		empty := 0
+
+		for file := 0; file < Size; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece.IsEmpty() {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				fmt.Fprint(&fen, empty)
+				empty = 0
+			}
+
+			fen.WriteByte(pieceChar(piece))
+		}
+
+		if empty > 0 {
+			fmt.Fprint(&fen, empty)
+		}
+
+		if rank > 0 {
+			fen.WriteByte('/')
+		}
+	}
+
+	turn := "w"
+	if p.Turn == Black {
+		turn = "b"
+	}
+
+	fmt.Fprintf(&fen, " %s %s %s %d %d", turn, p.Castling, p.EnPassant, p.HalfmoveClock, p.FullmoveNumber)
+
+	return fen.String()
+}
+
+// pieceChar returns the FEN character of piece
+func pieceChar(piece Piece) byte {
+	char := pieceChars[piece.Type]
+	if piece.Color == Black {
+		char += 'a' - 'A'
+	}
+
+	return char
+}
+
+// String returns the castling rights as they are written in FEN
+func (c CastlingRights) String() string {
+	rights := ""
+	for i, char := range "KQkq" {
+		if c.Has(WhiteKingside << i) {
+			rights += string(char)
+		}
+	}
+
+	if rights == "" {
+		return "-"
+	}
+
+	return rights
+}
+
+// FENError describes which field of a FEN string is invalid and why
+type FENError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FENError) Error() string {
+	return fmt.Sprintf("invalid FEN %s: %s", e.Field, e.Reason)
+}
+
+func fenError(field string, format string, a ...interface{}) error {
+	return &FENError{Field: field, Reason: fmt.Sprintf(format, a...)}
+}
+
+// StartingPosition returns the standard starting position
+func StartingPosition() *Position {
+	position, err := ParseFEN(StartingFEN)
+	if err != nil {
+		panic(err)
+	}
+
+	return position
+}
+
+// ParseFEN creates a position from a FEN string
+// returns a *FENError if the string is malformed or the position it describes is illegal
+func ParseFEN(fen string) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return nil, fenError("string", "expected 6 space separated fields but got %d", len(fields))
+	}
+
+	p := NewPosition()
+
+	if err := p.parsePiecePlacement(fields[0]); err != nil {
+		return nil, err
+	}
+
+	// current turn
+	switch fields[1] {
+	case "w":
+		p.Turn = White
+	case "b":
+		p.Turn = Black
+	default:
+		return nil, fenError("active color", "expected \"w\" or \"b\" but got %q", fields[1])
+	}
+
+	if err := p.parseCastlingRights(fields[2]); err != nil {
+		return nil, err
+	}
+
+	if err := p.parseEnPassant(fields[3]); err != nil {
+		return nil, err
+	}
+
+	// halfmoves and fullmoves
+	halfmoves, err := strconv.Atoi(fields[4])
+	if err != nil || halfmoves < 0 {
+		return nil, fenError("halfmove clock", "expected a non-negative number but got %q", fields[4])
+	}
+
+	fullmoves, err := strconv.Atoi(fields[5])
+	if err != nil || fullmoves < 1 {
+		return nil, fenError("fullmove number", "expected a positive number but got %q", fields[5])
+	}
+
+	p.HalfmoveClock = halfmoves
+	p.FullmoveNumber = fullmoves
+	p.RecomputeHash()
+
+	if p.IsInCheck(p.Turn.Opponent()) {
+		return nil, fenError("position", "%s is in check but it is %s's turn", p.Turn.Opponent(), p.Turn)
+	}
+
+	return p, nil
+}
+
+// parsePiecePlacement places the pieces described by the first field of a FEN string
+func (p *Position) parsePiecePlacement(placement string) error {
+	const field = "piece placement"
+
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != Size {
+		return fenError(field, "expected %d ranks but got %d", Size, len(ranks))
+	}
+
+	for i, fenRank := range ranks {
+		rank := Size - 1 - i
+		file := 0
+		lastWasDigit := false
+
+		for _, char := range fenRank {
+			if char >= '1' && char <= '8' {
+				if lastWasDigit {
+					return fenError(field, "consecutive digits in rank %d", rank+1)
+				}
+
+				file += int(char - '0')
+				lastWasDigit = true
+			} else {
+				piece, ok := pieceFromChar(char)
+				if !ok {
+					return fenError(field, "invalid character %q in rank %d", char, rank+1)
+				}
+
+				if file < Size {
+					p.SetPiece(NewSquare(file, rank), piece)
+				}
+
+				file++
+				lastWasDigit = false
+			}
+
+			if file > Size {
+				return fenError(field, "rank %d has more than %d squares", rank+1, Size)
+			}
+		}
+
+		if file != Size {
+			return fenError(field, "rank %d has %d squares instead of %d", rank+1, file, Size)
+		}
+	}
+
+	return p.validatePieces()
+}
+
+// pieceFromChar returns the piece written as char in FEN
+func pieceFromChar(char rune) (Piece, bool) {
+	color := White
+	if char >= 'a' && char <= 'z' {
+		color = Black
+		char -= 'a' - 'A'
+	}
+
+	index := strings.IndexRune(pieceChars, char)
+	if index <= int(NoPieceType) {
+		return NoPiece, false
+	}
+
+	return Piece{Type: PieceType(index), Color: color}, true
+}
+
+// validatePieces checks each side has one king and a number of pieces that can be reached in a game
+func (p *Position) validatePieces() error {
+	const field = "piece placement"
+
+	for _, color := range []Color{White, Black} {
+		counts := make(map[PieceType]int)
+		total := 0
+
+		for sq := Square(0); int(sq) < Size*Size; sq++ {
+			piece := p.board[sq]
+			if piece.IsEmpty() || piece.Color != color {
+				continue
+			}
+
+			if piece.Type == Pawn && (sq.Rank() == 0 || sq.Rank() == Size-1) {
+				return fenError(field, "%s pawn on %s cannot be on the first or last rank", color, sq)
+			}
+
+			counts[piece.Type]++
+			total++
+		}
+
+		if counts[King] != 1 {
+			return fenError(field, "%s must have exactly 1 king but has %d", color, counts[King])
+		}
+
+		if counts[Pawn] > Size {
+			return fenError(field, "%s has %d pawns, more than %d", color, counts[Pawn], Size)
+		}
+
+		if total > 2*Size {
+			return fenError(field, "%s has %d pieces, more than %d", color, total, 2*Size)
+		}
+
+		// every piece beyond the starting set must have come from a promoted pawn
+		promoted := extraPieces(counts[Queen], 1) + extraPieces(counts[Rook], 2) + extraPieces(counts[Bishop], 2) + extraPieces(counts[Knight], 2)
+		if promoted > Size-counts[Pawn] {
+			return fenError(field, "%s has %d promoted pieces but only %d missing pawns", color, promoted, Size-counts[Pawn])
+		}
+	}
+
+	return nil
+}
+
+func extraPieces(count int, starting int) int {
+	if count > starting {
+		return count - starting
+	}
+
+	return 0
+}
+
+// parseCastlingRights reads the castling field of a FEN string
+// each right requires the king and rook to still be on their starting squares
+func (p *Position) parseCastlingRights(castling string) error {
+	const field = "castling availability"
+
+	if castling == "-" {
+		return nil
+	}
+
+	for _, char := range castling {
+		index := strings.IndexRune("KQkq", char)
+		if index == -1 {
+			return fenError(field, "invalid character %q", char)
+		}
+
+		right := WhiteKingside << index
+		if p.Castling.Has(right) {
+			return fenError(field, "duplicate character %q", char)
+		}
+
+		color := White
+		rookFile := Size - 1
+		if right == WhiteQueenside || right == BlackQueenside {
+			rookFile = 0
+		}
+
+		if right == BlackKingside || right == BlackQueenside {
+			color = Black
+		}
+
+		rank := HomeRank(color)
+		if p.board[NewSquare(4, rank)] != (Piece{King, color}) || p.board[NewSquare(rookFile, rank)] != (Piece{Rook, color}) {
+			return fenError(field, "%q requires %s's king and rook on their starting squares", char, color)
+		}
+
+		p.Castling |= right
+	}
+
+	return nil
+}
+
+// parseEnPassant reads the en passant target field of a FEN string
+// the target must be behind a pawn that has just moved 2 squares
+func (p *Position) parseEnPassant(enPassant string) error {
+	const field = "en passant target"
+
+	if enPassant == "-" {
+		return nil
+	}
+
+	sq, err := ParseSquare(enPassant)
+	if err != nil {
+		return fenError(field, "%v", err)
+	}
+
+	mover := p.Turn.Opponent()
+	direction := PawnDirection(mover)
+	if sq.Rank() != HomeRank(mover)+2*direction {
+		return fenError(field, "%s is not on the rank behind a %s pawn that moved 2 squares", sq, mover)
+	}
+
+	pawn := NewSquare(sq.File(), sq.Rank()+direction)
+	start := NewSquare(sq.File(), sq.Rank()-direction)
+	if p.board[pawn] != (Piece{Pawn, mover}) || !p.board[sq].IsEmpty() || !p.board[start].IsEmpty() {
+		return fenError(field, "no %s pawn could have just moved 2 squares past %s", mover, sq)
+	}
+
+	p.EnPassant = sq
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package chess
+
+import "testing"
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1",
+		"r3k3/8/8/8/8/8/8/4K2R b K - 3 20",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Qk - 12 40",
+	}
+
+	for _, test := range perftTests {
+		fens = append(fens, test.fen)
+	}
+
+	for _, fen := range fens {
+		position, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", fen, err)
+		}
+
+		if got := position.FEN(); got != fen {
+			t.Errorf("ParseFEN(%q).FEN() = %q", fen, got)
+		}
+	}
+}
+
+func TestParseFENErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		field string
+	}{
+		{"too few fields", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", "string"},
+		{"too many fields", StartingFEN + " 1", "string"},
+		{"too few ranks", "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"short rank", "rnbqkbnr/pppppppp/8/8/7/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"long rank", "rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"consecutive digits", "rnbqkbnr/pppppppp/44/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"bad piece character", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1", "piece placement"},
+		{"no white king", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w kq - 0 1", "piece placement"},
+		{"two black kings", "rnbqkbnr/pppppppp/8/8/8/4k3/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "piece placement"},
+		{"pawn on the back rank", "rnbqkbnP/pppppppp/8/8/8/8/PPPPPPP1/RNBQKBNR w KQq - 0 1", "piece placement"},
+		{"bad active color", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", "active color"},
+		{"bad castling character", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1", "castling availability"},
+		{"castling without the rook", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1", "castling availability"},
+		{"castling without the king", "rnbq1bnr/ppppkppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "castling availability"},
+		{"en passant on the wrong rank", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e4 0 1", "en passant target"},
+		{"en passant without a pawn", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq e3 0 1", "en passant target"},
+		{"negative halfmove clock", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1", "halfmove clock"},
+		{"zero fullmove number", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", "fullmove number"},
+		{"side not to move in check", "4k3/8/8/8/8/8/8/4K2r b - - 0 1", "position"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFEN(test.fen)
+		fenErr, ok := err.(*FENError)
+		if !ok {
+			t.Errorf("%s: ParseFEN(%q) error = %v, want a *FENError", test.name, test.fen, err)
+			continue
+		}
+
+		if fenErr.Field != test.field {
+			t.Errorf("%s: ParseFEN(%q) error field = %q, want %q", test.name, test.fen, fenErr.Field, test.field)
+		}
+	}
+}
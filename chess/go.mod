@@ -0,0 +1,3 @@
+module github.com/freddie-nelson/chess/chess
+
+go 1.16
@@ -0,0 +1,216 @@
+package chess
+
+// The square by square move generator the bitboard generator replaced
+// kept to check the bitboard generator against and to benchmark it against
+
+var (
+	rookOffsets   = [][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	bishopOffsets = [][2]int{{1, 1}, {1, -1}, {-1, -1}, {-1, 1}}
+)
+
+// legalMovesMailbox returns every legal move for the side to move
+func (p *Position) legalMovesMailbox() []Move {
+	moves := p.pseudoLegalMovesMailbox()
+	legal := moves[:0]
+
+	for _, m := range moves {
+		simulated := *p
+		simulated.Apply(m)
+
+		if !simulated.isInCheckMailbox(p.Turn) {
+			legal = append(legal, m)
+		}
+	}
+
+	return legal
+}
+
+func (p *Position) isInCheckMailbox(color Color) bool {
+	king := NoSquare
+	for sq := Square(0); int(sq) < Size*Size; sq++ {
+		if p.board[sq] == (Piece{King, color}) {
+			king = sq
+		}
+	}
+
+	if king == NoSquare {
+		return false
+	}
+
+	return p.isSquareAttackedMailbox(king, color.Opponent())
+}
+
+func (p *Position) isSquareAttackedMailbox(sq Square, by Color) bool {
+	file := sq.File()
+	rank := sq.Rank()
+
+	// pawns attack diagonally forward so look backwards from sq
+	pawnRank := rank - PawnDirection(by)
+	for _, pawnFile := range []int{file - 1, file + 1} {
+		if IsOnBoard(pawnFile, pawnRank) && p.board[NewSquare(pawnFile, pawnRank)] == (Piece{Pawn, by}) {
+			return true
+		}
+	}
+
+	return p.isAttackedFromOffsets(file, rank, knightOffsets, 1, by, Knight, Knight) ||
+		p.isAttackedFromOffsets(file, rank, kingOffsets, 1, by, King, King) ||
+		p.isAttackedFromOffsets(file, rank, rookOffsets, Size, by, Rook, Queen) ||
+		p.isAttackedFromOffsets(file, rank, bishopOffsets, Size, by, Bishop, Queen)
+}
+
+func (p *Position) isAttackedFromOffsets(file int, rank int, offsets [][2]int, stopAfter int, by Color, a PieceType, b PieceType) bool {
+	for _, off := range offsets {
+		for i := 1; i <= stopAfter; i++ {
+			f := file + off[0]*i
+			r := rank + off[1]*i
+			if !IsOnBoard(f, r) {
+				break
+			}
+
+			piece := p.board[NewSquare(f, r)]
+			if piece.IsEmpty() {
+				continue
+			}
+
+			if piece.Color == by && (piece.Type == a || piece.Type == b) {
+				return true
+			}
+
+			break
+		}
+	}
+
+	return false
+}
+
+func (p *Position) pseudoLegalMovesMailbox() []Move {
+	moves := make([]Move, 0, 64)
+
+	for sq := Square(0); int(sq) < Size*Size; sq++ {
+		piece := p.board[sq]
+		if piece.IsEmpty() || piece.Color != p.Turn {
+			continue
+		}
+
+		switch piece.Type {
+		case King:
+			p.movesFromOffsets(&moves, sq, kingOffsets, 1)
+			p.castlingMovesMailbox(&moves, sq)
+		case Queen:
+			p.movesFromOffsets(&moves, sq, rookOffsets, Size)
+			p.movesFromOffsets(&moves, sq, bishopOffsets, Size)
+		case Rook:
+			p.movesFromOffsets(&moves, sq, rookOffsets, Size)
+		case Bishop:
+			p.movesFromOffsets(&moves, sq, bishopOffsets, Size)
+		case Knight:
+			p.movesFromOffsets(&moves, sq, knightOffsets, 1)
+		case Pawn:
+			p.pawnMovesMailbox(&moves, sq)
+		}
+	}
+
+	return moves
+}
+
+func (p *Position) movesFromOffsets(moves *[]Move, sq Square, offsets [][2]int, stopAfter int) {
+	for _, off := range offsets {
+		for i := 1; i <= stopAfter; i++ {
+			f := sq.File() + off[0]*i
+			r := sq.Rank() + off[1]*i
+			if !IsOnBoard(f, r) {
+				break
+			}
+
+			to := NewSquare(f, r)
+			piece := p.board[to]
+			if !piece.IsEmpty() {
+				if piece.Color != p.Turn {
+					*moves = append(*moves, Move{From: sq, To: to})
+				}
+
+				break
+			}
+
+			*moves = append(*moves, Move{From: sq, To: to})
+		}
+	}
+}
+
+func (p *Position) pawnMovesMailbox(moves *[]Move, sq Square) {
+	direction := PawnDirection(p.Turn)
+	file := sq.File()
+	nextRank := sq.Rank() + direction
+	if nextRank < 0 || nextRank >= Size {
+		return
+	}
+
+	to := NewSquare(file, nextRank)
+	if p.board[to].IsEmpty() {
+		addPawnMove(moves, sq, to)
+
+		doubleRank := nextRank + direction
+		if sq.Rank() == HomeRank(p.Turn)+direction && p.board[NewSquare(file, doubleRank)].IsEmpty() {
+			*moves = append(*moves, Move{From: sq, To: NewSquare(file, doubleRank)})
+		}
+	}
+
+	for _, captureFile := range []int{file - 1, file + 1} {
+		if !IsOnBoard(captureFile, nextRank) {
+			continue
+		}
+
+		to := NewSquare(captureFile, nextRank)
+		piece := p.board[to]
+		if (!piece.IsEmpty() && piece.Color != p.Turn) || to == p.EnPassant {
+			addPawnMove(moves, sq, to)
+		}
+	}
+}
+
+func (p *Position) castlingMovesMailbox(moves *[]Move, sq Square) {
+	color := p.Turn
+	rank := HomeRank(color)
+	if sq != NewSquare(4, rank) || p.isSquareAttackedMailbox(sq, color.Opponent()) {
+		return
+	}
+
+	for _, side := range []struct {
+		rights   CastlingRights
+		rookFile int
+		toFile   int
+	}{{KingsideRights(color), Size - 1, 6}, {QueensideRights(color), 0, 2}} {
+		if !p.Castling.Has(side.rights) || p.board[NewSquare(side.rookFile, rank)] != (Piece{Rook, color}) {
+			continue
+		}
+
+		direction := 1
+		if side.rookFile < sq.File() {
+			direction = -1
+		}
+
+		clear := true
+		for f := sq.File() + direction; f != side.rookFile; f += direction {
+			clear = clear && p.board[NewSquare(f, rank)].IsEmpty()
+		}
+
+		if clear && !p.isSquareAttackedMailbox(NewSquare(sq.File()+direction, rank), color.Opponent()) {
+			*moves = append(*moves, Move{From: sq, To: NewSquare(side.toFile, rank)})
+		}
+	}
+}
+
+func perftMailbox(p *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	nodes := 0
+	for _, m := range p.legalMovesMailbox() {
+		child := *p
+		child.Apply(m)
+		nodes += perftMailbox(&child, depth-1)
+	}
+
+	return nodes
+}
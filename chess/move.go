@@ -0,0 +1,19 @@
+package chess
+
+// Move is a move of a piece from one square to another
+// Promotion is the type a pawn becomes when it reaches the last rank
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+}
+
+// String returns the move in coordinate notation, e.g. "e2e4" or "e7e8q"
+func (m Move) String() string {
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string(pieceChar(Piece{Type: m.Promotion, Color: Black}))
+	}
+
+	return s
+}
@@ -0,0 +1,188 @@
+package chess
+
+// offsets are {file, rank} steps a piece can take
+var (
+	knightOffsets = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+)
+
+// LegalMoves returns every legal move for the side to move
+func (p *Position) LegalMoves() []Move {
+	moves := p.PseudoLegalMoves()
+	legal := moves[:0]
+	color := p.Turn
+
+	// play each move and keep it if it doesn't leave the king in check
+	for _, m := range moves {
+		u := p.MakeMove(m)
+		if !p.IsInCheck(color) {
+			legal = append(legal, m)
+		}
+
+		p.UnmakeMove(u)
+	}
+
+	return legal
+}
+
+// LegalMovesFrom returns every legal move for the piece on sq
+func (p *Position) LegalMovesFrom(sq Square) []Move {
+	moves := make([]Move, 0)
+	for _, m := range p.LegalMoves() {
+		if m.From == sq {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves
+}
+
+// IsLegal returns true if m is a legal move in the position
+func (p *Position) IsLegal(m Move) bool {
+	for _, legal := range p.LegalMoves() {
+		if legal == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsInCheck returns true if color's king is attacked
+func (p *Position) IsInCheck(color Color) bool {
+	king := p.KingSquare(color)
+	if king == NoSquare {
+		return false
+	}
+
+	return p.IsSquareAttacked(king, color.Opponent())
+}
+
+// IsSquareAttacked returns true if any piece of color by attacks sq
+func (p *Position) IsSquareAttacked(sq Square, by Color) bool {
+	pieces := &p.pieces[by]
+	occupied := p.Occupied()
+
+	// a pawn of by attacks sq from the squares a pawn of the other color on sq would attack
+	return pawnAttacks[by.Opponent()][sq]&pieces[Pawn] != 0 ||
+		knightAttacks[sq]&pieces[Knight] != 0 ||
+		kingAttacks[sq]&pieces[King] != 0 ||
+		rookAttacks(sq, occupied)&(pieces[Rook]|pieces[Queen]) != 0 ||
+		bishopAttacks(sq, occupied)&(pieces[Bishop]|pieces[Queen]) != 0
+}
+
+// PseudoLegalMoves returns every move for the side to move ignoring whether it leaves their king in check
+func (p *Position) PseudoLegalMoves() []Move {
+	moves := make([]Move, 0, 64)
+	targets := ^p.colors[p.Turn]
+	occupied := p.Occupied()
+
+	for pieceType := King; pieceType < Pawn; pieceType++ {
+		for from := p.pieces[p.Turn][pieceType]; from != 0; {
+			sq := from.PopLSB()
+
+			for to := attacksOf(pieceType, sq, occupied) & targets; to != 0; {
+				moves = append(moves, Move{From: sq, To: to.PopLSB()})
+			}
+		}
+	}
+
+	for from := p.pieces[p.Turn][Pawn]; from != 0; {
+		p.pawnMoves(&moves, from.PopLSB())
+	}
+
+	if king := p.KingSquare(p.Turn); king != NoSquare {
+		p.castlingMoves(&moves, king)
+	}
+
+	return moves
+}
+
+// pawnMoves adds the pushes, captures, en passant captures and promotions of the pawn on sq
+func (p *Position) pawnMoves(moves *[]Move, sq Square) {
+	direction := PawnDirection(p.Turn)
+	nextRank := sq.Rank() + direction
+	if nextRank < 0 || nextRank >= Size {
+		return
+	}
+
+	occupied := p.Occupied()
+
+	// pushes, pawns can move 2 ranks from their starting rank
+	to := NewSquare(sq.File(), nextRank)
+	if !occupied.Has(to) {
+		addPawnMove(moves, sq, to)
+
+		double := NewSquare(sq.File(), nextRank+direction)
+		if sq.Rank() == HomeRank(p.Turn)+direction && !occupied.Has(double) {
+			*moves = append(*moves, Move{From: sq, To: double})
+		}
+	}
+
+	// captures
+	targets := p.colors[p.Turn.Opponent()]
+	if p.EnPassant != NoSquare {
+		targets |= SquareBitboard(p.EnPassant)
+	}
+
+	for captures := pawnAttacks[p.Turn][sq] & targets; captures != 0; {
+		addPawnMove(moves, sq, captures.PopLSB())
+	}
+}
+
+// addPawnMove adds the move from -> to, expanding it into each promotion if the pawn reaches the last rank
+func addPawnMove(moves *[]Move, from Square, to Square) {
+	if to.Rank() != 0 && to.Rank() != Size-1 {
+		*moves = append(*moves, Move{From: from, To: to})
+		return
+	}
+
+	for _, promotion := range PromotionTypes {
+		*moves = append(*moves, Move{From: from, To: to, Promotion: promotion})
+	}
+}
+
+// castlingMoves adds the castling moves of the king on sq
+// the king's destination is checked for attacks when the moves are made legal
+func (p *Position) castlingMoves(moves *[]Move, sq Square) {
+	color := p.Turn
+	rank := HomeRank(color)
+	if sq != NewSquare(4, rank) || !p.Castling.Has(KingsideRights(color)) && !p.Castling.Has(QueensideRights(color)) {
+		return
+	}
+
+	// king cannot castle out of check
+	if p.IsSquareAttacked(sq, color.Opponent()) {
+		return
+	}
+
+	if p.Castling.Has(KingsideRights(color)) && p.canCastleWithRook(sq, Size-1) {
+		*moves = append(*moves, Move{From: sq, To: NewSquare(6, rank)})
+	}
+
+	if p.Castling.Has(QueensideRights(color)) && p.canCastleWithRook(sq, 0) {
+		*moves = append(*moves, Move{From: sq, To: NewSquare(2, rank)})
+	}
+}
+
+// canCastleWithRook returns true if the rook on rookFile is present, every square between it and the king is empty
+// and the king does not pass through an attacked square
+func (p *Position) canCastleWithRook(king Square, rookFile int) bool {
+	rank := king.Rank()
+	if p.board[NewSquare(rookFile, rank)] != (Piece{Rook, p.Turn}) {
+		return false
+	}
+
+	direction := 1
+	if rookFile < king.File() {
+		direction = -1
+	}
+
+	for f := king.File() + direction; f != rookFile; f += direction {
+		if !p.board[NewSquare(f, rank)].IsEmpty() {
+			return false
+		}
+	}
+
+	return !p.IsSquareAttacked(NewSquare(king.File()+direction, rank), p.Turn.Opponent())
+}
@@ -0,0 +1,109 @@
+package chess
+
+import (
+	"sort"
+	"testing"
+)
+
+func sortedMoveStrings(moves []Move) []string {
+	strs := make([]string, len(moves))
+	for i, m := range moves {
+		strs[i] = m.String()
+	}
+
+	sort.Strings(strs)
+
+	return strs
+}
+
+// checkMatchesMailbox walks the move tree checking both generators find the same moves
+// and that unmaking each move restores the position exactly
+func checkMatchesMailbox(t *testing.T, p *Position, depth int) {
+	bitboard := sortedMoveStrings(p.LegalMoves())
+	mailbox := sortedMoveStrings(p.legalMovesMailbox())
+	if len(bitboard) != len(mailbox) {
+		t.Fatalf("%s: bitboard moves %v, mailbox moves %v", p.FEN(), bitboard, mailbox)
+	}
+
+	for i := range bitboard {
+		if bitboard[i] != mailbox[i] {
+			t.Fatalf("%s: bitboard moves %v, mailbox moves %v", p.FEN(), bitboard, mailbox)
+		}
+	}
+
+	if depth == 0 {
+		return
+	}
+
+	for _, m := range p.LegalMoves() {
+		before := *p
+		u := p.MakeMove(m)
+		checkMatchesMailbox(t, p, depth-1)
+		p.UnmakeMove(u)
+
+		if *p != before {
+			t.Fatalf("%s: unmaking %s gave %s", before.FEN(), m, p.FEN())
+		}
+	}
+}
+
+func TestBitboardMatchesMailbox(t *testing.T) {
+	for _, test := range perftTests {
+		position, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN: %v", err)
+		}
+
+		checkMatchesMailbox(t, position, 2)
+	}
+}
+
+func benchmarkPositions(b *testing.B) []*Position {
+	positions := make([]*Position, 0, len(perftTests))
+	for _, test := range perftTests {
+		position, err := ParseFEN(test.fen)
+		if err != nil {
+			b.Fatalf("ParseFEN: %v", err)
+		}
+
+		positions = append(positions, position)
+	}
+
+	return positions
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	positions := benchmarkPositions(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		positions[i%len(positions)].LegalMoves()
+	}
+}
+
+func BenchmarkLegalMovesMailbox(b *testing.B) {
+	positions := benchmarkPositions(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		positions[i%len(positions)].legalMovesMailbox()
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	position := benchmarkPositions(b)[1]
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Perft(position, 3)
+	}
+}
+
+func BenchmarkPerftMailbox(b *testing.B) {
+	position := benchmarkPositions(b)[1]
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		perftMailbox(position, 3)
+	}
+}
@@ -0,0 +1,38 @@
+package chess
+
+// Perft counts the positions reached by playing every sequence of legal moves depth plies deep
+func Perft(p *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, m := range moves {
+		u := p.MakeMove(m)
+		nodes += Perft(p, depth-1)
+		p.UnmakeMove(u)
+	}
+
+	return nodes
+}
+
+// Divide returns the perft count of the position after each legal move
+func Divide(p *Position, depth int) map[Move]int {
+	counts := make(map[Move]int)
+	if depth < 1 {
+		return counts
+	}
+
+	for _, m := range p.LegalMoves() {
+		u := p.MakeMove(m)
+		counts[m] = Perft(p, depth-1)
+		p.UnmakeMove(u)
+	}
+
+	return counts
+}
@@ -0,0 +1,211 @@
+package chess
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Game results as written in PGN
+const (
+	WhiteWins  string = "1-0"
+	BlackWins  string = "0-1"
+	Draw       string = "1/2-1/2"
+	NoResult   string = "*"
+	pgnLineLen int    = 80
+)
+
+// sevenTagRoster the tags every PGN game has, in the order they are written
+var sevenTagRoster []string = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Tag is a PGN tag pair
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// PGN is a recorded game with its tags and moves
+type PGN struct {
+	Tags []Tag
+
+	// Start is the position the game started from, nil for the standard starting position
+	Start *Position
+
+	// Comments[i] is written after Moves[i], empty comments are left out
+	Moves    []Move
+	Comments []string
+
+	Result string
+}
+
+// NewPGN returns a game with the seven tag roster filled with unknown values
+func NewPGN() *PGN {
+	g := &PGN{Result: NoResult}
+	for _, name := range sevenTagRoster {
+		g.SetTag(name, "?")
+	}
+
+	g.SetTag("Result", NoResult)
+
+	return g
+}
+
+// Tag returns the value of the tag name or an empty string if it is not set
+func (g *PGN) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+
+	return ""
+}
+
+// SetTag sets the value of the tag name, adding it after the existing tags if it is not set
+func (g *PGN) SetTag(name string, value string) {
+	for i, tag := range g.Tags {
+		if tag.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+
+	g.Tags = append(g.Tags, Tag{name, value})
+}
+
+// StartPosition returns a copy of the position the game started from
+func (g *PGN) StartPosition() *Position {
+	if g.Start == nil {
+		return StartingPosition()
+	}
+
+	start := *g.Start
+	return &start
+}
+
+// AddMove appends m to the game with a comment, which can be empty
+func (g *PGN) AddMove(m Move, comment string) {
+	g.Moves = append(g.Moves, m)
+	g.Comments = append(g.Comments, comment)
+}
+
+// WriteTo writes the game to w in PGN export format
+func (g *PGN) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, g.String())
+	return int64(n), err
+}
+
+func (g *PGN) String() string {
+	var out strings.Builder
+
+	for _, tag := range g.exportTags() {
+		fmt.Fprintf(&out, "[%s \"%s\"]\n", tag.Name, escapeTagValue(tag.Value))
+	}
+
+	out.WriteString("\n")
+	out.WriteString(wrapMovetext(g.movetextTokens()))
+	out.WriteString("\n\n")
+
+	return out.String()
+}
+
+// exportTags returns the seven tag roster followed by the setup tags and any other tags in the order they were added
+func (g *PGN) exportTags() []Tag {
+	tags := make([]Tag, 0, len(g.Tags)+2)
+	for _, name := range sevenTagRoster {
+		value := g.Tag(name)
+		if name == "Result" {
+			value = g.Result
+		}
+
+		tags = append(tags, Tag{name, value})
+	}
+
+	if g.Start != nil {
+		tags = append(tags, Tag{"SetUp", "1"}, Tag{"FEN", g.Start.FEN()})
+	}
+
+	for _, tag := range g.Tags {
+		if !isSevenTagRoster(tag.Name) && tag.Name != "SetUp" && tag.Name != "FEN" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// movetextTokens replays the game returning the move numbers, SAN moves, comments and result
+func (g *PGN) movetextTokens() []string {
+	position := g.StartPosition()
+	tokens := make([]string, 0, len(g.Moves)*2+1)
+	needsNumber := true
+
+	for i, m := range g.Moves {
+		if position.Turn == White {
+			tokens = append(tokens, fmt.Sprintf("%d.", position.FullmoveNumber))
+		} else if needsNumber {
+			tokens = append(tokens, fmt.Sprintf("%d...", position.FullmoveNumber))
+		}
+
+		tokens = append(tokens, position.SAN(m))
+		position.Apply(m)
+
+		// black's move needs a number again after a comment breaks up the move pair
+		needsNumber = false
+		if i < len(g.Comments) && g.Comments[i] != "" {
+			tokens = append(tokens, "{"+strings.ReplaceAll(g.Comments[i], "}", ")")+"}")
+			needsNumber = true
+		}
+	}
+
+	return append(tokens, g.Result)
+}
+
+// wrapMovetext joins tokens with spaces, breaking lines before they exceed the PGN line length
+func wrapMovetext(tokens []string) string {
+	var out strings.Builder
+	lineLen := 0
+
+	for _, token := range tokens {
+		if lineLen > 0 && lineLen+1+len(token) > pgnLineLen {
+			out.WriteString("\n")
+			lineLen = 0
+		} else if lineLen > 0 {
+			out.WriteString(" ")
+			lineLen++
+		}
+
+		out.WriteString(token)
+		lineLen += len(token)
+	}
+
+	return out.String()
+}
+
+func escapeTagValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, roster := range sevenTagRoster {
+		if roster == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClockComment returns a PGN clock comment for ms milliseconds remaining, e.g. "[%clk 0:09:58.3]"
+func ClockComment(ms int) string {
+	if ms < 0 {
+		ms = 0
+	}
+
+	hours := ms / 3600000
+	mins := ms / 60000 % 60
+	secs := ms / 1000 % 60
+	tenths := ms / 100 % 10
+
+	return fmt.Sprintf("[%%clk %d:%02d:%02d.%d]", hours, mins, secs, tenths)
+}
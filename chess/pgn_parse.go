@@ -0,0 +1,263 @@
+package chess
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// moveNumberPattern matches a move number at the start of a movetext token, e.g. "12." or "12..."
+var moveNumberPattern = regexp.MustCompile(`^[0-9]+\.*`)
+
+// pgnParser reads games from PGN text
+type pgnParser struct {
+	input string
+	pos   int
+	line  int
+}
+
+// ParsePGN reads every game in r
+// comments are attached to the move they follow while NAGs and variations are skipped
+func ParsePGN(r io.Reader) ([]*PGN, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pgnParser{input: string(data), line: 1}
+	games := make([]*PGN, 0)
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+
+		game, err := p.parseGame()
+		if err != nil {
+			return nil, fmt.Errorf("pgn game %d: %v", len(games)+1, err)
+		}
+
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+func (p *pgnParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *pgnParser) peek() byte {
+	return p.input[p.pos]
+}
+
+func (p *pgnParser) next() byte {
+	c := p.input[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+
+	return c
+}
+
+func (p *pgnParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+// skipSpace skips whitespace and escaped lines starting with %
+func (p *pgnParser) skipSpace() {
+	for !p.eof() {
+		c := p.peek()
+		if c == '%' && (p.pos == 0 || p.input[p.pos-1] == '\n') {
+			p.skipLine()
+		} else if unicode.IsSpace(rune(c)) {
+			p.next()
+		} else {
+			return
+		}
+	}
+}
+
+func (p *pgnParser) skipLine() string {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+
+	return p.input[start:p.pos]
+}
+
+// parseGame reads the tags and movetext of one game
+func (p *pgnParser) parseGame() (*PGN, error) {
+	game := &PGN{Result: NoResult}
+
+	for p.skipSpace(); !p.eof() && p.peek() == '['; p.skipSpace() {
+		tag, err := p.parseTag()
+		if err != nil {
+			return nil, err
+		}
+
+		game.SetTag(tag.Name, tag.Value)
+	}
+
+	position := StartingPosition()
+	if fen := game.Tag("FEN"); fen != "" {
+		start, err := ParseFEN(fen)
+		if err != nil {
+			return nil, err
+		}
+
+		game.Start = start
+		position = game.StartPosition()
+	}
+
+	if result := game.Tag("Result"); result != "" {
+		game.Result = result
+	}
+
+	for p.skipSpace(); !p.eof() && p.peek() != '['; p.skipSpace() {
+		switch p.peek() {
+		case '{', ';':
+			comment := strings.TrimSpace(p.parseComment())
+			if len(game.Comments) > 0 && comment != "" {
+				last := len(game.Comments) - 1
+				game.Comments[last] = strings.TrimSpace(game.Comments[last] + " " + comment)
+			}
+		case '(':
+			if err := p.skipVariation(); err != nil {
+				return nil, err
+			}
+		case ')':
+			return nil, p.errorf("unexpected ')'")
+		case '$':
+			p.next()
+			p.parseSymbol()
+		default:
+			symbol := p.parseSymbol()
+			if symbol == "" {
+				return nil, p.errorf("unexpected %q", p.peek())
+			}
+
+			switch symbol {
+			case WhiteWins, BlackWins, Draw, NoResult:
+				game.Result = symbol
+				return game, nil
+			}
+
+			// move numbers can be written directly before the move, e.g. "1.e4"
+			san := moveNumberPattern.ReplaceAllString(symbol, "")
+			if san == "" {
+				continue
+			}
+
+			m, err := position.ParseSAN(san)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+
+			position.Apply(m)
+			game.AddMove(m, "")
+		}
+	}
+
+	return game, nil
+}
+
+// parseTag reads a tag pair such as [White "Freddie"]
+func (p *pgnParser) parseTag() (Tag, error) {
+	p.next()
+	p.skipSpace()
+
+	name := p.parseSymbol()
+	if name == "" {
+		return Tag{}, p.errorf("missing tag name")
+	}
+
+	p.skipSpace()
+	if p.eof() || p.next() != '"' {
+		return Tag{}, p.errorf("missing value of tag %s", name)
+	}
+
+	var value strings.Builder
+	for {
+		if p.eof() {
+			return Tag{}, p.errorf("unterminated value of tag %s", name)
+		}
+
+		c := p.next()
+		if c == '"' {
+			break
+		}
+
+		if c == '\\' && !p.eof() {
+			c = p.next()
+		}
+
+		value.WriteByte(c)
+	}
+
+	p.skipSpace()
+	if p.eof() || p.next() != ']' {
+		return Tag{}, p.errorf("missing ']' after tag %s", name)
+	}
+
+	return Tag{name, value.String()}, nil
+}
+
+// parseComment reads a {brace} comment or a ;rest of line comment
+func (p *pgnParser) parseComment() string {
+	if p.next() == ';' {
+		return p.skipLine()
+	}
+
+	start := p.pos
+	for !p.eof() && p.peek() != '}' {
+		p.next()
+	}
+
+	comment := p.input[start:p.pos]
+	if !p.eof() {
+		p.next()
+	}
+
+	return comment
+}
+
+// skipVariation skips a (variation) including any variations and comments nested inside it
+func (p *pgnParser) skipVariation() error {
+	depth := 0
+
+	for !p.eof() {
+		switch p.peek() {
+		case '(':
+			depth++
+			p.next()
+		case ')':
+			depth--
+			p.next()
+			if depth == 0 {
+				return nil
+			}
+		case '{', ';':
+			p.parseComment()
+		default:
+			p.next()
+		}
+	}
+
+	return p.errorf("unterminated variation")
+}
+
+// parseSymbol reads a token up to the next whitespace or delimiter
+func (p *pgnParser) parseSymbol() string {
+	start := p.pos
+	for !p.eof() && !unicode.IsSpace(rune(p.peek())) && !strings.ContainsRune("{}()[];$\"", rune(p.peek())) {
+		p.next()
+	}
+
+	return p.input[start:p.pos]
+}
@@ -0,0 +1,204 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPGN = `[Event "Casual game"]
+[Site "?"]
+[Date "2026.10.18"]
+[Round "-"]
+[White "Freddie"]
+[Black "GM \"Hikaru\""]
+[Result "0-1"]
+
+1. f3 {[%clk 0:10:00.0]} e5 $2 (1... d5 {solid} 2. g4 (2. e4) e5) 2.g4?? ; blunder
+Qh4# 0-1
+
+% escaped line
+[Event "Second"]
+[Result "*"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1"]
+
+1. O-O-O Ke7 *
+`
+
+func TestParsePGN(t *testing.T) {
+	games, err := ParsePGN(strings.NewReader(testPGN))
+	if err != nil {
+		t.Fatalf("ParsePGN: %v", err)
+	}
+
+	if len(games) != 2 {
+		t.Fatalf("got %d games, want 2", len(games))
+	}
+
+	first := games[0]
+	if first.Tag("Black") != `GM "Hikaru"` || first.Result != BlackWins || len(first.Moves) != 4 {
+		t.Errorf("first game = %q, %q, %d moves", first.Tag("Black"), first.Result, len(first.Moves))
+	}
+
+	if first.Comments[0] != "[%clk 0:10:00.0]" || first.Comments[2] != "blunder" {
+		t.Errorf("first game comments = %q", first.Comments)
+	}
+
+	second := games[1]
+	if second.Start == nil || len(second.Moves) != 2 || second.Moves[0].UCI() != "e1c1" {
+		t.Errorf("second game = %v, %v", second.Start, second.Moves)
+	}
+
+	// games written back out should read back the same
+	reread, err := ParsePGN(strings.NewReader(first.String() + second.String()))
+	if err != nil {
+		t.Fatalf("ParsePGN of written games: %v", err)
+	}
+
+	for i, game := range games {
+		if reread[i].String() != game.String() {
+			t.Errorf("game %d changed after writing and reading:\n%s\n%s", i+1, game, reread[i])
+		}
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+	for _, pgn := range []string{
+		`[Event "unterminated]`,
+		`1. e4 e5 2. Ke3 *`,
+		`1. e4 (1. d4 *`,
+		`[FEN "8/8/8/8/8/8/8/8 w - - 0 1"] *`,
+		`1. e4 } e5 *`,
+		`1. e4 ] e5 *`,
+		`1. e4 " e5 *`,
+	} {
+		if _, err := ParsePGN(strings.NewReader(pgn)); err == nil {
+			t.Errorf("ParsePGN(%q) succeeded, want error", pgn)
+		}
+	}
+}
+
+// playSAN plays moves given in SAN on g from it's start position
+func playSAN(t *testing.T, g *PGN, comments []string, moves ...string) {
+	t.Helper()
+
+	position := g.StartPosition()
+	for i, san := range moves {
+		m, err := position.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("ParseSAN(%q): %v", san, err)
+		}
+
+		comment := ""
+		if i < len(comments) {
+			comment = comments[i]
+		}
+
+		position.Apply(m)
+		g.AddMove(m, comment)
+	}
+}
+
+func TestPGNTagOrder(t *testing.T) {
+	g := NewPGN()
+	g.SetTag("TimeControl", "300+3")
+	g.SetTag("White", "Freddie")
+	g.SetTag("Black", `GM "Hikaru"`)
+	g.SetTag("Date", "2026.10.18")
+	g.Result = WhiteWins
+
+	start, err := ParseFEN("6k1/5ppp/8/8/8/8/8/R3K3 w Q - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Start = start
+	playSAN(t, g, nil, "Ra8#")
+
+	want := `[Event "?"]
+[Site "?"]
+[Date "2026.10.18"]
+[Round "?"]
+[White "Freddie"]
+[Black "GM \"Hikaru\""]
+[Result "1-0"]
+[SetUp "1"]
+[FEN "6k1/5ppp/8/8/8/8/8/R3K3 w Q - 0 1"]
+[TimeControl "300+3"]
+
+1. Ra8# 1-0
+
+`
+
+	if got := g.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPGNMovetext(t *testing.T) {
+	tests := []struct {
+		name     string
+		moves    []string
+		comments []string
+		want     string
+	}{
+		{
+			"no comments",
+			[]string{"e4", "e5", "Nf3", "Nc6"},
+			nil,
+			"1. e4 e5 2. Nf3 Nc6 *",
+		},
+		{
+			"black's move after a comment is numbered",
+			[]string{"e4", "e5", "Nf3", "Nc6"},
+			[]string{"", "", "good", ""},
+			"1. e4 e5 2. Nf3 {good} 2... Nc6 *",
+		},
+		{
+			"clock comments wrapped at 80 columns",
+			[]string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"},
+			[]string{
+				ClockComment(599000), ClockComment(598000), ClockComment(597000), ClockComment(596000),
+				ClockComment(595000), ClockComment(594000), ClockComment(593000), ClockComment(592000),
+			},
+			"1. Nf3 {[%clk 0:09:59.0]} 1... Nf6 {[%clk 0:09:58.0]} 2. Ng1 {[%clk 0:09:57.0]}\n" +
+				"2... Ng8 {[%clk 0:09:56.0]} 3. Nf3 {[%clk 0:09:55.0]} 3... Nf6\n" +
+				"{[%clk 0:09:54.0]} 4. Ng1 {[%clk 0:09:53.0]} 4... Ng8 {[%clk 0:09:52.0]} *",
+		},
+	}
+
+	for _, test := range tests {
+		g := NewPGN()
+		playSAN(t, g, test.comments, test.moves...)
+
+		got := g.String()
+		movetext := strings.TrimSuffix(got[strings.Index(got, "\n\n")+2:], "\n\n")
+		if movetext != test.want {
+			t.Errorf("%s: movetext =\n%s\nwant\n%s", test.name, movetext, test.want)
+		}
+
+		for _, line := range strings.Split(movetext, "\n") {
+			if len(line) > 80 {
+				t.Errorf("%s: line %q is longer than 80 columns", test.name, line)
+			}
+		}
+	}
+}
+
+func TestClockComment(t *testing.T) {
+	tests := []struct {
+		ms   int
+		want string
+	}{
+		{0, "[%clk 0:00:00.0]"},
+		{598300, "[%clk 0:09:58.3]"},
+		{3723456, "[%clk 1:02:03.4]"},
+		{-500, "[%clk 0:00:00.0]"},
+	}
+
+	for _, test := range tests {
+		if got := ClockComment(test.ms); got != test.want {
+			t.Errorf("ClockComment(%d) = %q, want %q", test.ms, got, test.want)
+		}
+	}
+}
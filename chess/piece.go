@@ -0,0 +1,58 @@
+package chess
+
+// Color is the color of a piece or player
+type Color int
+
+// Enum color of piece
+const (
+	White Color = iota
+	Black
+)
+
+// Opponent returns the color playing against c
+func (c Color) Opponent() Color {
+	if c == White {
+		return Black
+	}
+
+	return White
+}
+
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+
+	return "black"
+}
+
+// PieceType is the kind of a piece
+type PieceType int
+
+// Enum type of piece
+const (
+	NoPieceType PieceType = iota
+	King
+	Queen
+	Rook
+	Bishop
+	Knight
+	Pawn
+)
+
+// PromotionTypes the types a pawn can be promoted to
+var PromotionTypes []PieceType = []PieceType{Queen, Rook, Bishop, Knight}
+
+// Piece is a piece of a color on the board, the zero value is an empty square
+type Piece struct {
+	Type  PieceType
+	Color Color
+}
+
+// NoPiece represents an empty square
+var NoPiece = Piece{}
+
+// IsEmpty returns true if p represents an empty square
+func (p Piece) IsEmpty() bool {
+	return p.Type == NoPieceType
+}
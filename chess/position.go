@@ -0,0 +1,295 @@
+package chess
+
+// CastlingRights stores which sides each player can still castle on
+type CastlingRights int
+
+// Castling rights flags
+const (
+	WhiteKingside CastlingRights = 1 << iota
+	WhiteQueenside
+	BlackKingside
+	BlackQueenside
+
+	NoCastling CastlingRights = 0
+)
+
+// Has returns true if all of rights are held
+func (c CastlingRights) Has(rights CastlingRights) bool {
+	return c&rights == rights
+}
+
+// KingsideRights returns the kingside castling right of color
+func KingsideRights(color Color) CastlingRights {
+	if color == White {
+		return WhiteKingside
+	}
+
+	return BlackKingside
+}
+
+// QueensideRights returns the queenside castling right of color
+func QueensideRights(color Color) CastlingRights {
+	if color == White {
+		return WhiteQueenside
+	}
+
+	return BlackQueenside
+}
+
+// Position stores everything about a game needed to generate and play moves
+type Position struct {
+	board [Size * Size]Piece
+	hash  uint64
+
+	// bitboards of the squares of each color's pieces by type and of all their pieces
+	pieces [2][Pawn + 1]Bitboard
+	colors [2]Bitboard
+
+	Turn           Color
+	Castling       CastlingRights
+	EnPassant      Square
+	HalfmoveClock  int
+	FullmoveNumber int
+}
+
+// NewPosition returns an empty board with white to move
+func NewPosition() *Position {
+	p := &Position{
+		Turn:           White,
+		EnPassant:      NoSquare,
+		FullmoveNumber: 1,
+	}
+
+	p.RecomputeHash()
+
+	return p
+}
+
+// PieceAt returns the piece on sq
+func (p *Position) PieceAt(sq Square) Piece {
+	return p.board[sq]
+}
+
+// SetPiece places piece on sq, use NoPiece to empty the square
+func (p *Position) SetPiece(sq Square, piece Piece) {
+	old := p.board[sq]
+	p.hash ^= zobristPieceKey(old, sq) ^ zobristPieceKey(piece, sq)
+	p.board[sq] = piece
+
+	bit := SquareBitboard(sq)
+	if !old.IsEmpty() {
+		p.pieces[old.Color][old.Type] &^= bit
+		p.colors[old.Color] &^= bit
+	}
+
+	if !piece.IsEmpty() {
+		p.pieces[piece.Color][piece.Type] |= bit
+		p.colors[piece.Color] |= bit
+	}
+}
+
+// Pieces returns the squares of color's pieces of pieceType
+func (p *Position) Pieces(color Color, pieceType PieceType) Bitboard {
+	return p.pieces[color][pieceType]
+}
+
+// Occupied returns the squares that contain a piece
+func (p *Position) Occupied() Bitboard {
+	return p.colors[White] | p.colors[Black]
+}
+
+// KingSquare returns the square of color's king or NoSquare if there is no king
+func (p *Position) KingSquare(color Color) Square {
+	kings := p.pieces[color][King]
+	if kings == 0 {
+		return NoSquare
+	}
+
+	return kings.PopLSB()
+}
+
+// HomeRank returns the rank color's pieces start on
+func HomeRank(color Color) int {
+	if color == White {
+		return 0
+	}
+
+	return Size - 1
+}
+
+// PawnDirection returns the rank offset a pawn of color moves forward by
+func PawnDirection(color Color) int {
+	if color == White {
+		return 1
+	}
+
+	return -1
+}
+
+// Apply plays m on the position without checking that it is legal
+func (p *Position) Apply(m Move) {
+	piece := p.board[m.From]
+	captured := p.board[m.To]
+
+	// remove the state that is about to change from the hash, pieces are updated by SetPiece
+	p.hash ^= p.zobristStateKey()
+
+	p.SetPiece(m.From, NoPiece)
+
+	// en passant captures the pawn behind the target square
+	if piece.Type == Pawn && m.To == p.EnPassant {
+		passantSquare := NewSquare(m.To.File(), m.From.Rank())
+		captured = p.board[passantSquare]
+		p.SetPiece(passantSquare, NoPiece)
+	}
+
+	// king moving 2 files is castling so move the rook to the other side of the king
+	if piece.Type == King && (m.To.File()-m.From.File() == 2 || m.From.File()-m.To.File() == 2) {
+		rookFrom := NewSquare(Size-1, m.From.Rank())
+		rookTo := NewSquare(m.To.File()-1, m.From.Rank())
+		if m.To.File() < m.From.File() {
+			rookFrom = NewSquare(0, m.From.Rank())
+			rookTo = NewSquare(m.To.File()+1, m.From.Rank())
+		}
+
+		p.SetPiece(rookTo, p.board[rookFrom])
+		p.SetPiece(rookFrom, NoPiece)
+	}
+
+	if m.Promotion != NoPieceType {
+		piece.Type = m.Promotion
+	}
+
+	p.SetPiece(m.To, piece)
+
+	// revoke castling rights when the king moves or a rook leaves or is captured on it's starting square
+	if piece.Type == King {
+		p.Castling &^= KingsideRights(piece.Color) | QueensideRights(piece.Color)
+	}
+
+	p.Castling &^= castlingRightsOfSquare(m.From) | castlingRightsOfSquare(m.To)
+
+	// pawn moving 2 ranks makes the square it skipped the en passant target
+	p.EnPassant = NoSquare
+	if piece.Type == Pawn && (m.To.Rank()-m.From.Rank() == 2 || m.From.Rank()-m.To.Rank() == 2) {
+		p.EnPassant = NewSquare(m.From.File(), (m.From.Rank()+m.To.Rank())/2)
+	}
+
+	if piece.Type == Pawn || !captured.IsEmpty() {
+		p.HalfmoveClock = 0
+	} else {
+		p.HalfmoveClock++
+	}
+
+	if p.Turn == Black {
+		p.FullmoveNumber++
+	}
+
+	p.Turn = p.Turn.Opponent()
+	p.hash ^= p.zobristStateKey()
+}
+
+// Undo stores everything needed to take back a move exactly
+type Undo struct {
+	Move Move
+
+	captured      Piece
+	castling      CastlingRights
+	enPassant     Square
+	halfmoveClock int
+	hash          uint64
+}
+
+// MakeMove plays m like Apply and returns the record UnmakeMove needs to take it back
+func (p *Position) MakeMove(m Move) Undo {
+	u := Undo{
+		Move:          m,
+		captured:      p.board[m.To],
+		castling:      p.Castling,
+		enPassant:     p.EnPassant,
+		halfmoveClock: p.HalfmoveClock,
+		hash:          p.hash,
+	}
+
+	if p.board[m.From].Type == Pawn && m.To == p.EnPassant {
+		u.captured = p.board[NewSquare(m.To.File(), m.From.Rank())]
+	}
+
+	p.Apply(m)
+
+	return u
+}
+
+// UnmakeMove takes back the move recorded in u, which must be the last move played by MakeMove
+func (p *Position) UnmakeMove(u Undo) {
+	m := u.Move
+
+	p.Turn = p.Turn.Opponent()
+	if p.Turn == Black {
+		p.FullmoveNumber--
+	}
+
+	piece := p.board[m.To]
+	if m.Promotion != NoPieceType {
+		piece.Type = Pawn
+	}
+
+	p.SetPiece(m.From, piece)
+
+	// put back captured piece, en passant captures were behind the destination
+	if piece.Type == Pawn && m.To == u.enPassant {
+		p.SetPiece(m.To, NoPiece)
+		p.SetPiece(NewSquare(m.To.File(), m.From.Rank()), u.captured)
+	} else {
+		p.SetPiece(m.To, u.captured)
+	}
+
+	// move the rook back to it's corner after castling
+	if piece.Type == King && (m.To.File()-m.From.File() == 2 || m.From.File()-m.To.File() == 2) {
+		rookFrom := NewSquare(Size-1, m.From.Rank())
+		rookTo := NewSquare(m.To.File()-1, m.From.Rank())
+		if m.To.File() < m.From.File() {
+			rookFrom = NewSquare(0, m.From.Rank())
+			rookTo = NewSquare(m.To.File()+1, m.From.Rank())
+		}
+
+		p.SetPiece(rookFrom, p.board[rookTo])
+		p.SetPiece(rookTo, NoPiece)
+	}
+
+	p.Castling = u.castling
+	p.EnPassant = u.enPassant
+	p.HalfmoveClock = u.halfmoveClock
+	p.hash = u.hash
+}
+
+// TakebackPlies returns the number of moves to take back so it is requester's turn again
+// or 0 if requester has not made a move to take back, played is the number of moves made so far
+func (p *Position) TakebackPlies(requester Color, played int) int {
+	plies := 1
+	if p.Turn == requester {
+		plies = 2
+	}
+
+	if played < plies {
+		return 0
+	}
+
+	return plies
+}
+
+// castlingRightsOfSquare returns the castling rights that depend on a rook being on sq
+func castlingRightsOfSquare(sq Square) CastlingRights {
+	switch sq {
+	case NewSquare(0, 0):
+		return WhiteQueenside
+	case NewSquare(Size-1, 0):
+		return WhiteKingside
+	case NewSquare(0, Size-1):
+		return BlackQueenside
+	case NewSquare(Size-1, Size-1):
+		return BlackKingside
+	}
+
+	return NoCastling
+}
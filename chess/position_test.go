@@ -0,0 +1,28 @@
+package chess
+
+import "testing"
+
+func TestTakebackPlies(t *testing.T) {
+	tests := []struct {
+		name      string
+		turn      Color
+		requester Color
+		played    int
+		want      int
+	}{
+		{"opponent to move", Black, White, 1, 1},
+		{"requester to move", White, White, 2, 2},
+		{"no moves made", White, White, 0, 0},
+		{"requester has not moved", Black, Black, 1, 0},
+		{"opponent moved first", White, Black, 1, 1},
+	}
+
+	for _, test := range tests {
+		p := StartingPosition()
+		p.Turn = test.turn
+
+		if got := p.TakebackPlies(test.requester, test.played); got != test.want {
+			t.Errorf("%s: TakebackPlies(%s, %d) = %d, want %d", test.name, test.requester, test.played, got, test.want)
+		}
+	}
+}
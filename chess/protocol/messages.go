@@ -0,0 +1,331 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/freddie-nelson/chess/chess"
+)
+
+// MaxNameLength is the most characters a player's name can have
+const MaxNameLength = 32
+
+// MaxChatLength is the most characters a chat message can have
+const MaxChatLength = 200
+
+// messages returns a new empty message of each type
+var messages = map[string]func() Message{
+	"hello":           func() Message { return &Hello{} },
+	"welcome":         func() Message { return &Welcome{} },
+	"createRoom":      func() Message { return &CreateRoom{} },
+	"roomCreated":     func() Message { return &RoomCreated{} },
+	"joinRoom":        func() Message { return &JoinRoom{} },
+	"gameStart":       func() Message { return &GameStart{} },
+	"resume":          func() Message { return &Resume{} },
+	"gameState":       func() Message { return &GameState{} },
+	"opponentStatus":  func() Message { return &OpponentStatus{} },
+	"move":            func() Message { return &Move{} },
+	"moveMade":        func() Message { return &MoveMade{} },
+	"clockSync":       func() Message { return &ClockSync{} },
+	"ping":            func() Message { return &Ping{} },
+	"pong":            func() Message { return &Pong{} },
+	"drawOffer":       func() Message { return &DrawOffer{} },
+	"drawAnswer":      func() Message { return &DrawAnswer{} },
+	"resign":          func() Message { return &Resign{} },
+	"takebackRequest": func() Message { return &TakebackRequest{} },
+	"takebackAnswer":  func() Message { return &TakebackAnswer{} },
+	"chat":            func() Message { return &Chat{} },
+	"gameOver":        func() Message { return &GameOver{} },
+	"error":           func() Message { return &Error{} },
+}
+
+// Hello is the first message a client sends, with the protocol version it speaks and the player's name
+type Hello struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// Welcome accepts a client's hello with the protocol version the server speaks
+type Welcome struct {
+	Version int `json:"version"`
+}
+
+// CreateRoom asks the server for a new room
+type CreateRoom struct {
+	TimeControl string `json:"timeControl"`
+
+	// FEN of the position to start from, the standard starting position if empty
+	FEN string `json:"fen,omitempty"`
+}
+
+// RoomCreated tells the creator of a room the code others can join it with
+type RoomCreated struct {
+	Code string `json:"code"`
+}
+
+// JoinRoom asks to join the room with code
+type JoinRoom struct {
+	Code string `json:"code"`
+}
+
+// GameStart tells a player the game in their room has started and which color they are
+// token lets them resume the game if they lose their connection
+type GameStart struct {
+	Code        string `json:"code"`
+	Color       string `json:"color"`
+	Opponent    string `json:"opponent"`
+	FEN         string `json:"fen"`
+	TimeControl string `json:"timeControl"`
+	Token       string `json:"token"`
+}
+
+// Resume asks to rejoin the game the token was given for after losing the connection
+type Resume struct {
+	Token string `json:"token"`
+}
+
+// GameState tells a player who resumed a game everything needed to carry on playing it
+// moves are in UCI from the start position and clocks are in milliseconds
+type GameState struct {
+	Code              string   `json:"code"`
+	Color             string   `json:"color"`
+	Opponent          string   `json:"opponent"`
+	StartFEN          string   `json:"startFen"`
+	Moves             []string `json:"moves"`
+	FEN               string   `json:"fen"`
+	TimeControl       string   `json:"timeControl"`
+	WhiteClock        int      `json:"whiteClock"`
+	BlackClock        int      `json:"blackClock"`
+	OpponentConnected bool     `json:"opponentConnected"`
+	Token             string   `json:"token"`
+}
+
+// OpponentStatus tells a player their opponent lost their connection or came back
+// grace is how many milliseconds the opponent has to come back before they lose
+type OpponentStatus struct {
+	Connected bool `json:"connected"`
+	Grace     int  `json:"grace,omitempty"`
+}
+
+// Move is a move made by the player in UCI notation
+type Move struct {
+	Move string `json:"move"`
+}
+
+// MoveMade tells both players a move was made and the time each player has left in milliseconds
+// as kept by the server's clock once the move was made
+type MoveMade struct {
+	Move       string `json:"move"`
+	WhiteClock int    `json:"whiteClock"`
+	BlackClock int    `json:"blackClock"`
+}
+
+// ClockSync tells both players the time each player has left in milliseconds
+type ClockSync struct {
+	WhiteClock int `json:"whiteClock"`
+	BlackClock int `json:"blackClock"`
+}
+
+// Ping is sent by the server to measure the round trip time to the client
+type Ping struct {
+	ID int `json:"id"`
+}
+
+// Pong answers the ping with the same id
+type Pong struct {
+	ID int `json:"id"`
+}
+
+// DrawOffer offers the opponent a draw, or claims one if the player to move can
+// the server passes offers on to the opponent
+type DrawOffer struct{}
+
+// DrawAnswer accepts or declines the opponent's draw offer
+// the server passes declined offers on to the player who made them
+type DrawAnswer struct {
+	Accept bool `json:"accept"`
+}
+
+// Resign resigns the game
+type Resign struct{}
+
+// TakebackRequest asks the opponent to let the player take back their last move
+type TakebackRequest struct{}
+
+// TakebackAnswer accepts or declines the opponent's takeback request
+type TakebackAnswer struct {
+	Accept bool `json:"accept"`
+}
+
+// Chat is a message to the opponent, the server fills in who it is from
+type Chat struct {
+	From string `json:"from,omitempty"`
+	Text string `json:"text"`
+}
+
+// GameOver tells both players the game has ended
+// loser is the player who was checkmated, resigned or ran out of time and is empty for draws
+type GameOver struct {
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+	Loser  string `json:"loser,omitempty"`
+}
+
+// Error tells the client their last message could not be handled
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Type returns the name the message is sent with
+func (*Hello) Type() string { return "hello" }
+
+// Type returns the name the message is sent with
+func (*Welcome) Type() string { return "welcome" }
+
+// Type returns the name the message is sent with
+func (*CreateRoom) Type() string { return "createRoom" }
+
+// Type returns the name the message is sent with
+func (*RoomCreated) Type() string { return "roomCreated" }
+
+// Type returns the name the message is sent with
+func (*JoinRoom) Type() string { return "joinRoom" }
+
+// Type returns the name the message is sent with
+func (*GameStart) Type() string { return "gameStart" }
+
+// Type returns the name the message is sent with
+func (*Resume) Type() string { return "resume" }
+
+// Type returns the name the message is sent with
+func (*GameState) Type() string { return "gameState" }
+
+// Type returns the name the message is sent with
+func (*OpponentStatus) Type() string { return "opponentStatus" }
+
+// Type returns the name the message is sent with
+func (*Move) Type() string { return "move" }
+
+// Type returns the name the message is sent with
+func (*MoveMade) Type() string { return "moveMade" }
+
+// Type returns the name the message is sent with
+func (*ClockSync) Type() string { return "clockSync" }
+
+// Type returns the name the message is sent with
+func (*Ping) Type() string { return "ping" }
+
+// Type returns the name the message is sent with
+func (*Pong) Type() string { return "pong" }
+
+// Type returns the name the message is sent with
+func (*DrawOffer) Type() string { return "drawOffer" }
+
+// Type returns the name the message is sent with
+func (*DrawAnswer) Type() string { return "drawAnswer" }
+
+// Type returns the name the message is sent with
+func (*Resign) Type() string { return "resign" }
+
+// Type returns the name the message is sent with
+func (*TakebackRequest) Type() string { return "takebackRequest" }
+
+// Type returns the name the message is sent with
+func (*TakebackAnswer) Type() string { return "takebackAnswer" }
+
+// Type returns the name the message is sent with
+func (*Chat) Type() string { return "chat" }
+
+// Type returns the name the message is sent with
+func (*GameOver) Type() string { return "gameOver" }
+
+// Type returns the name the message is sent with
+func (*Error) Type() string { return "error" }
+
+// Validate checks the hello has a name
+// the version is checked by the server so clients too old to send one are told to update
+func (m *Hello) Validate() error {
+	return validateName(m.Name)
+}
+
+// Validate checks the time control and starting position can be parsed
+func (m *CreateRoom) Validate() error {
+	if _, err := chess.ParseTimeControl(m.TimeControl); err != nil {
+		return err
+	}
+
+	if m.FEN != "" {
+		if _, err := chess.ParseFEN(m.FEN); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks there is a room code
+func (m *JoinRoom) Validate() error {
+	if m.Code == "" || len(m.Code) > 16 {
+		return errors.New("invalid room code")
+	}
+
+	return nil
+}
+
+// Validate checks there is a token
+func (m *Resume) Validate() error {
+	if m.Token == "" {
+		return errors.New("missing token")
+	}
+
+	return nil
+}
+
+// Validate checks the move looks like UCI, whether it is legal is checked by the server
+func (m *Move) Validate() error {
+	if len(m.Move) != 4 && len(m.Move) != 5 {
+		return errors.New("invalid move")
+	}
+
+	return nil
+}
+
+// Validate checks the chat message isn't empty, too long or able to mess with the opponent's terminal
+func (m *Chat) Validate() error {
+	if m.Text == "" || utf8.RuneCountInString(m.Text) > MaxChatLength {
+		return fmt.Errorf("chat text must be between 1 and %v characters", MaxChatLength)
+	}
+
+	if hasControl(m.Text) {
+		return errors.New("chat text can't contain control characters")
+	}
+
+	return nil
+}
+
+// validateName checks a player's name isn't empty, too long or able to mess with the opponent's terminal
+func validateName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return fmt.Errorf("name must be between 1 and %v characters", MaxNameLength)
+	}
+
+	if hasControl(name) {
+		return errors.New("name can't contain control characters")
+	}
+
+	return nil
+}
+
+// hasControl returns true if s contains a control character such as the escape that starts terminal sequences
+func hasControl(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,203 @@
+// Package protocol is the format of the messages sent between the chess client and server
+// each message is a JSON object on it's own line with the message's type and data, see PROTOCOL.md
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/freddie-nelson/chess/chess"
+)
+
+// Version is the version of the protocol spoken by this package
+const Version = 4
+
+// MinVersion is the oldest version of the protocol that is still accepted
+// older clients can't read the resume token sent with gameStart
+const MinVersion = 3
+
+// MaxMessageSize is the longest line a message can be sent on
+const MaxMessageSize = 64 * 1024
+
+// Error codes sent in Error messages
+const (
+	// ErrBadMessage is sent when a message can't be decoded or has invalid fields
+	ErrBadMessage = "bad_message"
+
+	// ErrUnsupportedVersion is sent when the client's protocol version isn't supported, the connection is then closed
+	ErrUnsupportedVersion = "unsupported_version"
+
+	// ErrHelloRequired is sent when a message other than hello is sent before the client has said hello
+	ErrHelloRequired = "hello_required"
+
+	// ErrRejected is sent when a message is valid but can't be acted on, such as a move out of turn
+	ErrRejected = "rejected"
+)
+
+// Message is a message sent between the client and server
+type Message interface {
+	// Type returns the name the message is sent with
+	Type() string
+}
+
+// validator is a message that can check it's fields are valid once decoded
+type validator interface {
+	Validate() error
+}
+
+// envelope is how a message is written on the wire
+type envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// DecodeError is returned when a message that was received can't be decoded
+// the connection can still be used after one
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the reason the message couldn't be decoded
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ParseColor parses a color sent in a message
+func ParseColor(s string) (chess.Color, error) {
+	switch s {
+	case chess.White.String():
+		return chess.White, nil
+	case chess.Black.String():
+		return chess.Black, nil
+	}
+
+	return chess.White, fmt.Errorf("invalid color %q", s)
+}
+
+// Conn sends and receives messages over a connection
+// Send is safe to call from multiple goroutines
+type Conn struct {
+	rw      io.ReadWriter
+	scanner *bufio.Scanner
+
+	mu sync.Mutex
+}
+
+// NewConn returns a Conn that sends and receives messages over rw
+func NewConn(rw io.ReadWriter) *Conn {
+	scanner := bufio.NewScanner(rw)
+	scanner.Buffer(make([]byte, 0, 4096), MaxMessageSize)
+
+	return &Conn{rw: rw, scanner: scanner}
+}
+
+// Send writes msg to the connection
+func (c *Conn) Send(msg Message) error {
+	line, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = c.rw.Write(append(line, '\n'))
+	return err
+}
+
+// Receive reads the next message from the connection
+// messages that can't be decoded return a *DecodeError and any other error means the connection has failed
+func (c *Conn) Receive() (Message, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+
+	msg, err := Decode(c.scanner.Bytes())
+	if err != nil {
+		return nil, &DecodeError{err}
+	}
+
+	return msg, nil
+}
+
+// Close closes the underlying connection if it can be closed
+func (c *Conn) Close() error {
+	if closer, ok := c.rw.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Encode returns msg as it is written on the wire, without the trailing newline
+func Encode(msg Message) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope{msg.Type(), data})
+}
+
+// Decode parses a single message
+// unknown types and fields, trailing data and invalid field values are all rejected
+func Decode(line []byte) (Message, error) {
+	var env envelope
+	if err := decodeStrict(line, &env); err != nil {
+		return nil, fmt.Errorf("invalid message: %v", err)
+	}
+
+	if env.Type == "" {
+		return nil, errors.New("invalid message: missing type")
+	}
+
+	newMessage, ok := messages[env.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type %q", env.Type)
+	}
+
+	msg := newMessage()
+	if len(env.Data) != 0 && !bytes.Equal(env.Data, []byte("null")) {
+		if err := decodeStrict(env.Data, msg); err != nil {
+			return nil, fmt.Errorf("invalid %s message: %v", env.Type, err)
+		}
+	}
+
+	if v, ok := msg.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s message: %v", env.Type, err)
+		}
+	}
+
+	return msg, nil
+}
+
+// decodeStrict unmarshals data into v rejecting unknown fields and anything after the value
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	// More misses stray closing brackets so the rest must be empty
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after message")
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []Message{
+		&Hello{Version: Version, Name: "Freddie"},
+		&CreateRoom{TimeControl: "5+3"},
+		&CreateRoom{TimeControl: "5+3", FEN: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+		&JoinRoom{Code: "ABC234"},
+		&Move{Move: "e7e8q"},
+		&Ping{ID: 3},
+		&MoveMade{Move: "e2e4", WhiteClock: 1, BlackClock: 2},
+		&DrawOffer{},
+		&Resume{Token: "abc"},
+		&GameState{Code: "ABC234", Color: "white", Moves: []string{"e2e4", "e7e5"}, WhiteClock: 1, OpponentConnected: true},
+		&Chat{From: "Freddie", Text: "good game"},
+		&GameOver{Result: "1-0", Reason: "resignation", Loser: "black"},
+		&Error{Code: ErrRejected, Message: "not your turn"},
+	}
+
+	for _, msg := range tests {
+		line, err := Encode(msg)
+		if err != nil {
+			t.Fatalf("Encode(%+v): %v", msg, err)
+		}
+
+		got, err := Decode(line)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", line, err)
+		}
+
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("Decode(%s) = %+v, want %+v", line, got, msg)
+		}
+	}
+}
+
+func TestDecodeIsStrict(t *testing.T) {
+	tests := []string{
+		``,
+		`not json`,
+		`{}`,
+		`{"type": "castle"}`,
+		`{"type": "move", "data": {"move": "e2e4", "promotion": "q"}}`,
+		`{"type": "move", "data": {"move": 42}}`,
+		`{"type": "move", "data": {"move": "e2"}}`,
+		`{"type": "move", "data": {"move": "e2e4"}} {"type": "resign"}`,
+		`{"type": "resign", "extra": true}`,
+		`{"type": "resign"}}`,
+		`{"type": "resign"}]`,
+		`{"type": "move", "data": {"move": "e2e4"}}}`,
+		`{"type": "hello", "data": {"version": 3}}`,
+		`{"type": "resume", "data": {}}`,
+		`{"type": "move", "data": {"move": "e2e4", "clock": 1000}}`,
+		`{"type": "hello", "data": {"version": 1, "name": ""}}`,
+		`{"type": "createRoom", "data": {"timeControl": "fast"}}`,
+		`{"type": "createRoom", "data": {"timeControl": "5+3", "fen": "8/8/8/8/8/8/8/8 w - - 0 1"}}`,
+		`{"type": "chat", "data": {"text": ""}}`,
+		`{"type": "chat", "data": {"text": "\u001b[2Jgg"}}`,
+		`{"type": "chat", "data": {"text": "line\nbreak"}}`,
+		`{"type": "hello", "data": {"version": 4, "name": "\u001b[31mred"}}`,
+		`{"type": "hello", "data": {"version": 4, "name": "bell\u0007"}}`,
+	}
+
+	for _, line := range tests {
+		if msg, err := Decode([]byte(line)); err == nil {
+			t.Errorf("Decode(%s) = %+v, should fail", line, msg)
+		}
+	}
+}
+
+func TestConnReceiveReportsDecodeErrors(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("{\"type\": \"castle\"}\n")
+
+	c := NewConn(&buf)
+	if err := c.Send(&Resign{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Receive(); err == nil {
+		t.Fatal("unknown message type should fail")
+	} else if _, ok := err.(*DecodeError); !ok {
+		t.Fatalf("got %T, want *DecodeError", err)
+	}
+
+	msg, err := c.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := msg.(*Resign); !ok {
+		t.Fatalf("got %T, want *Resign", msg)
+	}
+}
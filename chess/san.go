@@ -0,0 +1,154 @@
+package chess
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sanPattern matches a non castling SAN move once check marks and annotations are removed
+// groups are piece, from file, from rank, destination and promotion
+var sanPattern = regexp.MustCompile(`^([KQRBN])?([a-h])?([1-8])?x?([a-h][1-8])(?:=?([QRBN]))?$`)
+
+// SAN returns m written in standard algebraic notation, m must be legal in the position
+func (p *Position) SAN(m Move) string {
+	piece := p.board[m.From]
+	san := ""
+
+	if piece.Type == King && (m.To.File()-m.From.File() == 2 || m.From.File()-m.To.File() == 2) {
+		san = "O-O"
+		if m.To.File() < m.From.File() {
+			san = "O-O-O"
+		}
+	} else {
+		isCapture := !p.board[m.To].IsEmpty() || (piece.Type == Pawn && m.To == p.EnPassant)
+
+		if piece.Type == Pawn {
+			if isCapture {
+				san += string(rune('a' + m.From.File()))
+			}
+		} else {
+			san += string(pieceChars[piece.Type]) + p.disambiguation(m)
+		}
+
+		if isCapture {
+			san += "x"
+		}
+
+		san += m.To.String()
+
+		if m.Promotion != NoPieceType {
+			san += "=" + string(pieceChars[m.Promotion])
+		}
+	}
+
+	// check and checkmate suffixes
+	after := *p
+	after.Apply(m)
+	if after.IsInCheck(after.Turn) {
+		if len(after.LegalMoves()) == 0 {
+			san += "#"
+		} else {
+			san += "+"
+		}
+	}
+
+	return san
+}
+
+// disambiguation returns the file, rank or square of m's start needed to tell it apart
+// from other pieces of the same type that can move to the same square
+func (p *Position) disambiguation(m Move) string {
+	piece := p.board[m.From]
+	ambiguous, sameFile, sameRank := false, false, false
+
+	for _, other := range p.LegalMoves() {
+		if other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+
+		ambiguous = true
+		sameFile = sameFile || other.From.File() == m.From.File()
+		sameRank = sameRank || other.From.Rank() == m.From.Rank()
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return m.From.String()[:1]
+	case !sameRank:
+		return m.From.String()[1:]
+	default:
+		return m.From.String()
+	}
+}
+
+// ParseSAN returns the legal move written as san in standard algebraic notation, e.g. "Nbd7" or "exd6"
+// returns an error if san is malformed, illegal or ambiguous in the position
+func (p *Position) ParseSAN(san string) (Move, error) {
+	text := strings.TrimRight(strings.TrimSpace(san), "+#!?")
+	text = strings.TrimSuffix(text, "e.p.")
+
+	legalMoves := p.LegalMoves()
+
+	// castling, also accepting zeros which are often used instead of the letter O
+	switch strings.ReplaceAll(text, "0", "O") {
+	case "O-O", "O-O-O":
+		king := p.KingSquare(p.Turn)
+		file := 6
+		if len(text) == 5 {
+			file = 2
+		}
+
+		for _, m := range legalMoves {
+			if m.From == king && m.To == NewSquare(file, king.Rank()) {
+				return m, nil
+			}
+		}
+
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	}
+
+	groups := sanPattern.FindStringSubmatch(text)
+	if groups == nil {
+		return Move{}, fmt.Errorf("invalid SAN move %q", san)
+	}
+
+	pieceType := Pawn
+	if groups[1] != "" {
+		pieceType = PieceType(strings.Index(pieceChars, groups[1]))
+	}
+
+	to, _ := ParseSquare(groups[4])
+	promotion := NoPieceType
+	if groups[5] != "" {
+		promotion = PieceType(strings.Index(pieceChars, groups[5]))
+	}
+
+	var found []Move
+	for _, m := range legalMoves {
+		if m.To != to || m.Promotion != promotion || p.board[m.From].Type != pieceType {
+			continue
+		}
+
+		if groups[2] != "" && m.From.File() != int(groups[2][0]-'a') {
+			continue
+		}
+
+		if groups[3] != "" && m.From.Rank() != int(groups[3][0]-'1') {
+			continue
+		}
+
+		found = append(found, m)
+	}
+
+	switch len(found) {
+	case 0:
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	case 1:
+		return found[0], nil
+	default:
+		return Move{}, fmt.Errorf("ambiguous move %q", san)
+	}
+}
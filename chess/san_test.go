@@ -0,0 +1,72 @@
+package chess
+
+import "testing"
+
+var sanTests = []struct {
+	fen  string
+	move string
+	san  string
+}{
+	{StartingFEN, "g1f3", "Nf3"},
+	{StartingFEN, "e2e4", "e4"},
+	{"r1bqkbnr/pppppppp/2n5/8/8/5N2/PPPPPPPP/RNBQKB1R w KQkq - 2 2", "b1c3", "Nc3"},
+	{"rnbqkbnr/pp1ppppp/8/2pP4/8/8/PPP1PPPP/RNBQKBNR w KQkq c6 0 3", "d5c6", "dxc6"},
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", "O-O-O"},
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "a1a8", "Rxa8+"},
+	{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+	{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "h1d1", "Rhd1"},
+	{"4k3/8/8/R7/8/8/8/R3K3 w Q - 0 1", "a1a3", "R1a3"},
+	{"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1", "a1a8", "Ra8+"},
+	{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1c3", "Qa1c3"},
+	{"6k1/5ppp/8/8/8/8/8/R3K3 w Q - 0 1", "a1a8", "Ra8#"},
+	{"8/1P2k3/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q"},
+	{"2r1k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7c8n", "bxc8=N"},
+}
+
+func TestSAN(t *testing.T) {
+	for _, test := range sanTests {
+		position, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", test.fen, err)
+		}
+
+		var move Move
+		for _, m := range position.LegalMoves() {
+			if m.String() == test.move {
+				move = m
+			}
+		}
+
+		if got := position.SAN(move); got != test.san {
+			t.Errorf("%s: SAN(%s) = %q, want %q", test.fen, test.move, got, test.san)
+		}
+
+		parsed, err := position.ParseSAN(test.san)
+		if err != nil || parsed != move {
+			t.Errorf("%s: ParseSAN(%q) = %v, %v, want %s", test.fen, test.san, parsed, err, test.move)
+		}
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	position, err := ParseFEN("r1bqkbnr/pppppppp/2n5/8/8/5N2/PPPPPPPP/RNBQKB1R w KQkq - 2 2")
+	if err != nil {
+		t.Fatalf("ParseFEN: %v", err)
+	}
+
+	for _, san := range []string{"", "Nd5", "e5", "O-O", "Zf3", "b8=Q"} {
+		if m, err := position.ParseSAN(san); err == nil {
+			t.Errorf("ParseSAN(%q) = %s, want error", san, m)
+		}
+	}
+
+	position, err = ParseFEN("4k3/8/8/8/8/8/4K3/R6R w - - 0 1")
+	if err != nil {
+		t.Fatalf("ParseFEN: %v", err)
+	}
+
+	if m, err := position.ParseSAN("Rd1"); err == nil {
+		t.Errorf("ParseSAN(\"Rd1\") = %s, want ambiguous move error", m)
+	}
+}
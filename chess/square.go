@@ -0,0 +1,49 @@
+package chess
+
+import "fmt"
+
+// Square is an index into the board where a1 is 0, b1 is 1 and h8 is 63
+type Square int
+
+// NoSquare is used when there is no square, e.g. no en passant target
+const NoSquare Square = -1
+
+// Size is the width and height of the board
+const Size int = 8
+
+// NewSquare returns the square on file (0 is the a file) and rank (0 is the first rank)
+func NewSquare(file int, rank int) Square {
+	return Square(rank*Size + file)
+}
+
+// File returns the file of s where 0 is the a file
+func (s Square) File() int {
+	return int(s) % Size
+}
+
+// Rank returns the rank of s where 0 is the first rank
+func (s Square) Rank() int {
+	return int(s) / Size
+}
+
+// IsOnBoard returns true if file and rank are on the board
+func IsOnBoard(file int, rank int) bool {
+	return file >= 0 && file < Size && rank >= 0 && rank < Size
+}
+
+func (s Square) String() string {
+	if s < 0 || int(s) >= Size*Size {
+		return "-"
+	}
+
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// ParseSquare returns the square named by s in algebraic notation, e.g. "e4"
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] >= 'a'+byte(Size) || s[1] < '1' || s[1] >= '1'+byte(Size) {
+		return NoSquare, fmt.Errorf("invalid square %q", s)
+	}
+
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
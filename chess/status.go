@@ -0,0 +1,130 @@
+package chess
+
+import "fmt"
+
+// Status is the state of a game, whether it is still being played or how it ended
+type Status int
+
+// Enum game status
+const (
+	Ongoing Status = iota
+	Checkmate
+	Stalemate
+	ThreefoldRepetition
+	FivefoldRepetition
+	FiftyMoveRule
+	SeventyFiveMoveRule
+	InsufficientMaterial
+	DrawAgreed
+	Resignation
+	Timeout
+
+	// TimeoutVsInsufficientMaterial is a draw when a player runs out of time but their opponent can't checkmate
+	TimeoutVsInsufficientMaterial
+)
+
+var statusStrings []string = []string{
+	"ongoing",
+	"checkmate",
+	"stalemate",
+	"threefold repetition",
+	"fivefold repetition",
+	"fifty-move rule",
+	"seventy-five-move rule",
+	"insufficient material",
+	"draw agreed",
+	"resignation",
+	"timeout",
+	"timeout vs insufficient material",
+}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusStrings) {
+		return "unknown"
+	}
+
+	return statusStrings[s]
+}
+
+// ParseStatus returns the status named s as written by String
+func ParseStatus(s string) (Status, error) {
+	for i, name := range statusStrings {
+		if name == s {
+			return Status(i), nil
+		}
+	}
+
+	return Ongoing, fmt.Errorf("unknown status %q", s)
+}
+
+// IsDraw returns true if the game ended in a draw
+func (s Status) IsDraw() bool {
+	switch s {
+	case Stalemate, ThreefoldRepetition, FivefoldRepetition, FiftyMoveRule, SeventyFiveMoveRule, InsufficientMaterial, DrawAgreed, TimeoutVsInsufficientMaterial:
+		return true
+	}
+
+	return false
+}
+
+// Result returns the PGN result of a game that ended with status
+// loser is the player who was checkmated, resigned or ran out of time and is ignored for draws
+func (s Status) Result(loser Color) string {
+	switch {
+	case s == Ongoing:
+		return NoResult
+	case s.IsDraw():
+		return Draw
+	case loser == White:
+		return BlackWins
+	default:
+		return WhiteWins
+	}
+}
+
+// Status returns the status of the game decided by the position alone
+// repetitions is the number of times the position has been reached in the game
+// draws that have to be claimed are not included, see ClaimableDraw
+func (p *Position) Status(repetitions int) Status {
+	if len(p.LegalMoves()) == 0 {
+		if p.IsInCheck(p.Turn) {
+			return Checkmate
+		}
+
+		return Stalemate
+	}
+
+	switch {
+	case repetitions >= FivefoldRepetitionCount:
+		return FivefoldRepetition
+	case p.HalfmoveClock >= SeventyFiveMoveHalfmoves:
+		return SeventyFiveMoveRule
+	case p.IsInsufficientMaterial():
+		return InsufficientMaterial
+	}
+
+	return Ongoing
+}
+
+// ClaimableDraw returns the draw the side to move can claim or Ongoing if there is none
+// repetitions is the number of times the position has been reached in the game
+func (p *Position) ClaimableDraw(repetitions int) Status {
+	switch {
+	case repetitions >= ThreefoldRepetitionCount:
+		return ThreefoldRepetition
+	case p.HalfmoveClock >= FiftyMoveHalfmoves:
+		return FiftyMoveRule
+	}
+
+	return Ongoing
+}
+
+// TimeoutStatus returns how the game ends when color runs out of time
+// it is a draw if their opponent doesn't have the material to checkmate
+func (p *Position) TimeoutStatus(color Color) Status {
+	if !p.CanCheckmate(color.Opponent()) {
+		return TimeoutVsInsufficientMaterial
+	}
+
+	return Timeout
+}
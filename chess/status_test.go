@@ -0,0 +1,71 @@
+package chess
+
+import "testing"
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		repetitions int
+		want        Status
+	}{
+		{"start position", StartingFEN, 1, Ongoing},
+		{"fool's mate", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", 1, Checkmate},
+		{"back rank mate", "R5k1/5ppp/8/8/8/8/8/4K3 b - - 1 1", 1, Checkmate},
+		{"smothered mate", "6rk/5Npp/8/8/8/8/8/4K3 b - - 1 1", 1, Checkmate},
+		{"check that can be blocked", "4k3/8/8/8/8/8/3r4/R3K3 w Q - 0 1", 1, Ongoing},
+		{"queen stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", 1, Stalemate},
+		{"blocked pawn stalemate", "8/8/8/8/8/5k2/5p2/5K2 w - - 0 1", 1, Stalemate},
+		{"boxed in king with a pawn move", "k7/8/1Q6/8/8/8/p7/4K3 b - - 0 1", 1, Ongoing},
+		{"mate on the seventy-fifth move", "R5k1/5ppp/8/8/8/8/8/4K3 b - - 150 90", 1, Checkmate},
+		{"seventy-five-move rule", "4k3/8/8/8/8/8/8/R3K3 w - - 150 90", 1, SeventyFiveMoveRule},
+		{"fivefold repetition", StartingFEN, 5, FivefoldRepetition},
+		{"threefold repetition is claimed", StartingFEN, 3, Ongoing},
+		{"king against king", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", 1, InsufficientMaterial},
+		{"king and knight against king", "4k3/8/8/8/8/8/8/2N1K3 w - - 0 1", 1, InsufficientMaterial},
+		{"same colored bishops", "2b1k3/8/8/8/8/8/8/3BK3 w - - 0 1", 1, InsufficientMaterial},
+		{"opposite colored bishops", "3bk3/8/8/8/8/8/8/3BK3 w - - 0 1", 1, Ongoing},
+	}
+
+	for _, test := range tests {
+		position, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%s: ParseFEN: %v", test.name, err)
+		}
+
+		if got := position.Status(test.repetitions); got != test.want {
+			t.Errorf("%s: Status() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestStatusResult(t *testing.T) {
+	if got := Checkmate.Result(Black); got != WhiteWins {
+		t.Errorf("Checkmate.Result(Black) = %q", got)
+	}
+
+	if got := Timeout.Result(White); got != BlackWins {
+		t.Errorf("Timeout.Result(White) = %q", got)
+	}
+
+	if got := Stalemate.Result(White); got != Draw {
+		t.Errorf("Stalemate.Result(White) = %q", got)
+	}
+
+	if got := Ongoing.Result(White); got != NoResult {
+		t.Errorf("Ongoing.Result(White) = %q", got)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for s := Ongoing; s <= TimeoutVsInsufficientMaterial; s++ {
+		got, err := ParseStatus(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseStatus(%q) = %v, %v, want %v", s.String(), got, err, s)
+		}
+	}
+
+	if _, err := ParseStatus("unknown"); err == nil {
+		t.Error("ParseStatus(\"unknown\") should fail")
+	}
+}
@@ -0,0 +1,243 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DelayType is how a time control's delay is applied
+type DelayType int
+
+// Enum delay type
+const (
+	// NoDelay is a time control without a delay
+	NoDelay DelayType = iota
+
+	// SimpleDelay waits for the delay before the player's clock starts running each move, also called US delay
+	SimpleDelay
+
+	// BronsteinDelay gives back the time used on a move up to the delay once the move is made
+	BronsteinDelay
+)
+
+// TimePeriod is one stage of a time control
+type TimePeriod struct {
+	// moves each player must make in the period, 0 if the period lasts the rest of the game
+	Moves int
+
+	Time      time.Duration
+	Increment time.Duration
+}
+
+// TimeControl describes how much time each player has
+// once the last period is over it is repeated, unless it lasts the rest of the game
+type TimeControl struct {
+	Periods   []TimePeriod
+	Delay     time.Duration
+	DelayType DelayType
+}
+
+// maxPeriodTime is the most time a period can give a player
+const maxPeriodTime = 24 * time.Hour
+
+// maxIncrement is the longest increment a period can have
+const maxIncrement = time.Hour
+
+// Preset is a named time control
+type Preset struct {
+	Name    string
+	Control string
+}
+
+// Presets are the standard time controls that can be chosen by name
+var Presets = []Preset{
+	{"bullet", "1+0"},
+	{"blitz", "5+3"},
+	{"rapid", "15+10"},
+	{"classical", "40/90+30,30+30"},
+	{"delay", "25+0,d5"},
+}
+
+// Initial returns the time each player starts with
+func (tc TimeControl) Initial() time.Duration {
+	if len(tc.Periods) == 0 {
+		return 0
+	}
+
+	return tc.Periods[0].Time
+}
+
+// period returns the index of the period a player's nth move is made in, counting from 0,
+// and whether it is the last move of the period
+func (tc TimeControl) period(n int) (int, bool) {
+	i := 0
+	for {
+		period := tc.Periods[i]
+		if period.Moves == 0 || n < period.Moves {
+			return i, period.Moves != 0 && n == period.Moves-1
+		}
+
+		n -= period.Moves
+		if i < len(tc.Periods)-1 {
+			i++
+		}
+	}
+}
+
+// String returns the time control in the format read by ParseTimeControl
+func (tc TimeControl) String() string {
+	periods := make([]string, len(tc.Periods))
+	for i, period := range tc.Periods {
+		s := ""
+		if period.Moves != 0 {
+			s = fmt.Sprintf("%v/", period.Moves)
+		}
+
+		s += strconv.FormatFloat(period.Time.Minutes(), 'f', -1, 64)
+		s += "+" + strconv.FormatFloat(period.Increment.Seconds(), 'f', -1, 64)
+
+		periods[i] = s
+	}
+
+	switch tc.DelayType {
+	case SimpleDelay:
+		periods = append(periods, "d"+strconv.FormatFloat(tc.Delay.Seconds(), 'f', -1, 64))
+	case BronsteinDelay:
+		periods = append(periods, "b"+strconv.FormatFloat(tc.Delay.Seconds(), 'f', -1, 64))
+	}
+
+	return strings.Join(periods, ",")
+}
+
+// PGNTag returns the time control as the value of a PGN TimeControl tag
+func (tc TimeControl) PGNTag() string {
+	if len(tc.Periods) == 0 {
+		return "-"
+	}
+
+	periods := make([]string, len(tc.Periods))
+	for i, period := range tc.Periods {
+		s := ""
+		if period.Moves != 0 {
+			s = fmt.Sprintf("%v/", period.Moves)
+		}
+
+		s += strconv.FormatFloat(period.Time.Seconds(), 'f', -1, 64)
+		if period.Increment != 0 {
+			s += "+" + strconv.FormatFloat(period.Increment.Seconds(), 'f', -1, 64)
+		}
+
+		periods[i] = s
+	}
+
+	return strings.Join(periods, ":")
+}
+
+// ParseTimeControl parses a time control such as "5+3" or the name of a preset
+// periods are separated by commas and written as [moves/]minutes[+increment seconds], e.g. "40/90+30,30+30"
+// a delay in seconds can follow the periods, "d" for a simple delay or "b" for a Bronstein delay, e.g. "25+0,d5"
+func ParseTimeControl(s string) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	for _, preset := range Presets {
+		if strings.EqualFold(s, preset.Name) {
+			s = preset.Control
+			break
+		}
+	}
+
+	if s == "" {
+		return TimeControl{}, errors.New("empty time control")
+	}
+
+	var tc TimeControl
+	fields := strings.Split(s, ",")
+	if last := strings.TrimSpace(fields[len(fields)-1]); len(fields) > 1 && len(last) > 0 && (last[0] == 'd' || last[0] == 'b') {
+		delay, err := parseAmount(last[1:], time.Second, maxIncrement)
+		if err != nil || delay <= 0 {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: invalid delay %q", s, last)
+		}
+
+		tc.Delay = delay
+		tc.DelayType = SimpleDelay
+		if last[0] == 'b' {
+			tc.DelayType = BronsteinDelay
+		}
+
+		fields = fields[:len(fields)-1]
+	}
+
+	for _, field := range fields {
+		period, err := parseTimePeriod(strings.TrimSpace(field))
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: %v", s, err)
+		}
+
+		tc.Periods = append(tc.Periods, period)
+	}
+
+	for _, period := range tc.Periods[:len(tc.Periods)-1] {
+		if period.Moves == 0 {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: only the last period can last the rest of the game", s)
+		}
+	}
+
+	return tc, nil
+}
+
+// parseTimePeriod parses a single period of a time control
+func parseTimePeriod(s string) (TimePeriod, error) {
+	var period TimePeriod
+
+	if i := strings.Index(s, "/"); i >= 0 {
+		moves, err := strconv.Atoi(s[:i])
+		if err != nil || moves <= 0 {
+			return period, fmt.Errorf("invalid move count %q", s[:i])
+		}
+
+		period.Moves = moves
+		s = s[i+1:]
+	}
+
+	minutes := s
+	increment := ""
+	hasIncrement := false
+	if i := strings.Index(s, "+"); i >= 0 {
+		minutes, increment, hasIncrement = s[:i], s[i+1:], true
+	}
+
+	m, err := parseAmount(minutes, time.Minute, maxPeriodTime)
+	if err != nil || m <= 0 {
+		return period, fmt.Errorf("invalid minutes %q", minutes)
+	}
+
+	period.Time = m
+
+	if hasIncrement {
+		inc, err := parseAmount(increment, time.Second, maxIncrement)
+		if err != nil {
+			return period, fmt.Errorf("invalid increment %q", increment)
+		}
+
+		period.Increment = inc
+	}
+
+	return period, nil
+}
+
+// parseAmount parses a non-negative number of units no longer than max
+func parseAmount(s string, unit time.Duration, max time.Duration) (time.Duration, error) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if math.IsNaN(n) || math.IsInf(n, 0) || n < 0 || n*float64(unit) > float64(max) {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+
+	return time.Duration(n * float64(unit)), nil
+}
@@ -0,0 +1,69 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		s         string
+		periods   []TimePeriod
+		delay     time.Duration
+		delayType DelayType
+		str       string
+		pgn       string
+	}{
+		{"5+3", []TimePeriod{{Time: 5 * time.Minute, Increment: 3 * time.Second}}, 0, NoDelay, "5+3", "300+3"},
+		{"15+10", []TimePeriod{{Time: 15 * time.Minute, Increment: 10 * time.Second}}, 0, NoDelay, "15+10", "900+10"},
+		{"10", []TimePeriod{{Time: 10 * time.Minute}}, 0, NoDelay, "10+0", "600"},
+		{"0.5+0", []TimePeriod{{Time: 30 * time.Second}}, 0, NoDelay, "0.5+0", "30"},
+		{"40/90+30", []TimePeriod{{Moves: 40, Time: 90 * time.Minute, Increment: 30 * time.Second}}, 0, NoDelay, "40/90+30", "40/5400+30"},
+		{"40/90+30,30+30", []TimePeriod{
+			{Moves: 40, Time: 90 * time.Minute, Increment: 30 * time.Second},
+			{Time: 30 * time.Minute, Increment: 30 * time.Second},
+		}, 0, NoDelay, "40/90+30,30+30", "40/5400+30:1800+30"},
+		{"25,d5", []TimePeriod{{Time: 25 * time.Minute}}, 5 * time.Second, SimpleDelay, "25+0,d5", "1500"},
+		{"5+0, b2.5", []TimePeriod{{Time: 5 * time.Minute}}, 2500 * time.Millisecond, BronsteinDelay, "5+0,b2.5", "300"},
+		{"40/90,30,d10", []TimePeriod{{Moves: 40, Time: 90 * time.Minute}, {Time: 30 * time.Minute}}, 10 * time.Second, SimpleDelay, "40/90+0,30+0,d10", "40/5400:1800"},
+		{"delay", []TimePeriod{{Time: 25 * time.Minute}}, 5 * time.Second, SimpleDelay, "25+0,d5", "1500"},
+		{"Blitz", []TimePeriod{{Time: 5 * time.Minute, Increment: 3 * time.Second}}, 0, NoDelay, "5+3", "300+3"},
+	}
+
+	for _, test := range tests {
+		tc, err := ParseTimeControl(test.s)
+		if err != nil {
+			t.Errorf("ParseTimeControl(%q): %v", test.s, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(tc.Periods, test.periods) {
+			t.Errorf("ParseTimeControl(%q) = %+v, want %+v", test.s, tc.Periods, test.periods)
+		}
+
+		if tc.Delay != test.delay || tc.DelayType != test.delayType {
+			t.Errorf("ParseTimeControl(%q) delay = %v %v, want %v %v", test.s, tc.Delay, tc.DelayType, test.delay, test.delayType)
+		}
+
+		if got := tc.String(); got != test.str {
+			t.Errorf("ParseTimeControl(%q).String() = %q, want %q", test.s, got, test.str)
+		}
+
+		if again, err := ParseTimeControl(tc.String()); err != nil || !reflect.DeepEqual(again, tc) {
+			t.Errorf("ParseTimeControl(%q) = %+v, %v, want %+v", tc.String(), again, err, tc)
+		}
+
+		if got := tc.PGNTag(); got != test.pgn {
+			t.Errorf("ParseTimeControl(%q).PGNTag() = %q, want %q", test.s, got, test.pgn)
+		}
+	}
+}
+
+func TestParseTimeControlErrors(t *testing.T) {
+	for _, s := range []string{"", "hyper", "0+3", "-5+3", "5+", "5+-1", "x/90", "0/90", "30+30,40/90+30", "5+3,", "nan", "inf", "-inf", "1e300", "5+nan", "5+inf", "5+1e300", "1441+0", "5+3601", "d5", "5+0,d", "5+0,d0", "5+0,dnan", "5+0,x5", "5+0,d5,b5"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Errorf("ParseTimeControl(%q) should fail", s)
+		}
+	}
+}
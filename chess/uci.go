@@ -0,0 +1,45 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UCI returns m in the long algebraic notation used by the UCI protocol, e.g. "e2e4" or "e7e8q"
+func (m Move) UCI() string {
+	return m.String()
+}
+
+// ParseUCI returns the legal move written as uci in long algebraic notation
+// returns an error if uci is malformed or not legal in the position
+func (p *Position) ParseUCI(uci string) (Move, error) {
+	if len(uci) != 4 && len(uci) != 5 {
+		return Move{}, fmt.Errorf("invalid UCI move %q", uci)
+	}
+
+	from, err := ParseSquare(uci[0:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move %q: %v", uci, err)
+	}
+
+	to, err := ParseSquare(uci[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move %q: %v", uci, err)
+	}
+
+	m := Move{From: from, To: to}
+	if len(uci) == 5 {
+		promotion := strings.IndexByte(pieceChars, uci[4]-('a'-'A'))
+		if promotion == -1 || PieceType(promotion) == King || PieceType(promotion) == Pawn || uci[4] < 'a' {
+			return Move{}, fmt.Errorf("invalid UCI move %q: bad promotion piece %q", uci, uci[4])
+		}
+
+		m.Promotion = PieceType(promotion)
+	}
+
+	if !p.IsLegal(m) {
+		return Move{}, fmt.Errorf("illegal move %q", uci)
+	}
+
+	return m, nil
+}
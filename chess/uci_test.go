@@ -0,0 +1,25 @@
+package chess
+
+import "testing"
+
+func TestParseUCI(t *testing.T) {
+	position, err := ParseFEN("r3k3/1P6/8/8/8/8/8/4K2R w K - 0 1")
+	if err != nil {
+		t.Fatalf("ParseFEN: %v", err)
+	}
+
+	for _, uci := range []string{"e1g1", "b7b8q", "b7a8n", "h1h8"} {
+		m, err := position.ParseUCI(uci)
+		if err != nil {
+			t.Errorf("ParseUCI(%q): %v", uci, err)
+		} else if m.UCI() != uci {
+			t.Errorf("ParseUCI(%q).UCI() = %q", uci, m.UCI())
+		}
+	}
+
+	for _, uci := range []string{"", "0000", "e1", "e1e9", "b7b8", "b7b8k", "b7b8Q", "e1c1", "e2e4", "e1g1q"} {
+		if m, err := position.ParseUCI(uci); err == nil {
+			t.Errorf("ParseUCI(%q) = %s, want error", uci, m)
+		}
+	}
+}
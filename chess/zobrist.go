@@ -0,0 +1,79 @@
+package chess
+
+// Zobrist keys xored together to make the hash of a position
+var (
+	zobristPieces    [2][Pawn + 1][Size * Size]uint64
+	zobristBlack     uint64
+	zobristCastling  [16]uint64
+	zobristEnPassant [Size]uint64
+)
+
+func init() {
+	// keys come from a fixed seed so hashes are the same in every program that uses the package
+	seed := uint64(0x9e3779b97f4a7c15)
+	next := func() uint64 {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+
+	for color := range zobristPieces {
+		for pieceType := King; pieceType <= Pawn; pieceType++ {
+			for sq := range zobristPieces[color][pieceType] {
+				zobristPieces[color][pieceType][sq] = next()
+			}
+		}
+	}
+
+	zobristBlack = next()
+
+	for rights := range zobristCastling {
+		zobristCastling[rights] = next()
+	}
+
+	for file := range zobristEnPassant {
+		zobristEnPassant[file] = next()
+	}
+}
+
+// Hash returns the Zobrist hash of the position
+// positions that count as repetitions of each other have the same hash
+// so the en passant file is only included when a pawn is able to take en passant
+func (p *Position) Hash() uint64 {
+	return p.hash
+}
+
+// RecomputeHash calculates the hash from scratch
+// it must be called after changing Turn, Castling or EnPassant directly
+func (p *Position) RecomputeHash() {
+	p.hash = p.zobristStateKey()
+	for sq, piece := range p.board {
+		p.hash ^= zobristPieceKey(piece, Square(sq))
+	}
+}
+
+// zobristPieceKey returns the key of piece on sq, empty squares have no key
+func zobristPieceKey(piece Piece, sq Square) uint64 {
+	if piece.IsEmpty() {
+		return 0
+	}
+
+	return zobristPieces[piece.Color][piece.Type][sq]
+}
+
+// zobristStateKey returns the part of the hash made from the side to move, castling rights and en passant file
+func (p *Position) zobristStateKey() uint64 {
+	key := zobristCastling[p.Castling]
+	if p.Turn == Black {
+		key ^= zobristBlack
+	}
+
+	if p.canTakeEnPassant() {
+		key ^= zobristEnPassant[p.EnPassant.File()]
+	}
+
+	return key
+}
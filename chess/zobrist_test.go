@@ -0,0 +1,69 @@
+package chess
+
+import "testing"
+
+// checkIncrementalHash walks the move tree checking the hash kept by Apply matches one calculated from scratch
+func checkIncrementalHash(t *testing.T, p *Position, depth int) {
+	recomputed := *p
+	recomputed.RecomputeHash()
+	if p.Hash() != recomputed.Hash() {
+		t.Fatalf("%s: incremental hash %x, recomputed %x", p.FEN(), p.Hash(), recomputed.Hash())
+	}
+
+	if depth == 0 {
+		return
+	}
+
+	for _, m := range p.LegalMoves() {
+		child := *p
+		child.Apply(m)
+		checkIncrementalHash(t, &child, depth-1)
+	}
+}
+
+func TestIncrementalHash(t *testing.T) {
+	for _, test := range perftTests {
+		position, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN: %v", err)
+		}
+
+		checkIncrementalHash(t, position, 2)
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	start := StartingPosition()
+
+	// knights out and back reaches the starting position again
+	position := StartingPosition()
+	for _, uci := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+		m, err := position.ParseUCI(uci)
+		if err != nil {
+			t.Fatalf("ParseUCI(%q): %v", uci, err)
+		}
+
+		position.Apply(m)
+	}
+
+	if position.Hash() != start.Hash() {
+		t.Errorf("hash after knight moves %x, want starting hash %x", position.Hash(), start.Hash())
+	}
+
+	// en passant target only changes the hash when it can be taken
+	withTarget, _ := ParseFEN("4k3/8/8/8/4P3/8/8/4K3 b - e3 0 1")
+	withoutTarget, _ := ParseFEN("4k3/8/8/8/4P3/8/8/4K3 b - - 0 1")
+	if withTarget.Hash() != withoutTarget.Hash() {
+		t.Errorf("en passant target that can't be taken changed the hash")
+	}
+
+	withTarget, _ = ParseFEN("4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1")
+	withoutTarget, _ = ParseFEN("4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1")
+	if withTarget.Hash() == withoutTarget.Hash() {
+		t.Errorf("en passant target that can be taken did not change the hash")
+	}
+
+	if StartingPosition().Hash() == NewPosition().Hash() {
+		t.Errorf("starting position has the same hash as an empty board")
+	}
+}
@@ -4,14 +4,20 @@ import (
 	"fmt"
 	"strings"
 	"time"
-	"unicode"
+
+	"github.com/freddie-nelson/chess/chess"
 )
 
 // Board handles game logic about the board and drawing board to console
 type Board struct {
 	grid         *[Size][Size]Spot
+	position     *chess.Position
 	selectedSpot *Spot
 	pickedSpot   *Spot
+
+	// move waiting for the player to choose a promotion piece
+	promotionMove   *chess.Move
+	promotionChoice int
 }
 
 // Setup creates the initial chess board
@@ -29,99 +35,40 @@ func (b *Board) Setup() {
 	b.grid = &board
 
 	// generate starting position
-	startingFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
-	b.GenerateFromFENString(startingFEN)
+	b.position = chess.StartingPosition()
 }
 
-// GenerateFromFENString creates a particular board position from a provided valid FEN string
-func (b *Board) GenerateFromFENString(fen string) {
-	piecePlacements := strings.Split(fen, "/")
-
-	last := strings.Split(piecePlacements[7], " ")
-	piecePlacements[7] = last[0]
-	fields := last[1:]
-
-	// current turn
-	if fields[0] == "b" {
-		Game.turn = Black
-	} else {
-		Game.turn = White
-	}
-
-	// castling rights
-	Game.blackCastling = &CastlingRights{false, false}
-	Game.whiteCastling = &CastlingRights{false, false}
-
-	for _, rights := range fields[1] {
-		if unicode.IsLower(rights) {
-			if rights == 'k' {
-				Game.blackCastling.kingside = true
-			} else {
-				Game.blackCastling.queenside = true
-			}
-		} else {
-			if rights == 'K' {
-				Game.whiteCastling.kingside = true
-			} else {
-				Game.whiteCastling.queenside = true
-			}
-		}
-	}
-
-	// en passant targets
-	if fields[2] != "-" {
-		file, rank := b.locationToFileAndRank(fields[2])
-		b.grid[file][rank].passantTarget = 2
+// GenerateFromFENString creates a particular board position from a provided FEN string
+// returns an error describing the problem if the FEN string is invalid, leaving the board unchanged
+func (b *Board) GenerateFromFENString(fen string) error {
+	position, err := chess.ParseFEN(fen)
+	if err != nil {
+		return err
 	}
 
-	// fullmoves and halfmoves
-	Game.halfmoves = int(fields[3][0] - '0')
-	Game.fullmoves = int(fields[4][0] - '0')
-
-	// place pieces
-	for rank, fenRank := range piecePlacements {
-		file := 0
-
-		for _, char := range fenRank {
-			var color int
-			var class int
+	b.position = position
+	return nil
+}
 
-			if unicode.IsNumber(char) {
-				file += int(char - '0')
-				continue
-			} else if unicode.IsLower(char) {
-				color = Black
-			} else {
-				color = White
-			}
+// ToFEN returns the current position as a FEN string that GenerateFromFENString can read back
+func (b *Board) ToFEN() string {
+	return b.position.FEN()
+}
 
-			switch unicode.ToUpper(char) {
-			case 'Q':
-				class = Queen
-			case 'K':
-				class = King
-			case 'R':
-				class = Rook
-			case 'B':
-				class = Bishop
-			case 'N':
-				class = Knight
-			case 'P':
-				class = Pawn
-			}
+// SpotSquare returns the square in the position that spot draws
+// spots count ranks from the top of the board while squares count from white's side
+func (b *Board) SpotSquare(spot *Spot) chess.Square {
+	return chess.NewSquare(spot.file, Size-1-spot.rank)
+}
 
-			b.grid[file][rank].containsPiece = true
-			b.grid[file][rank].piece = &Piece{color: color, class: class}
-			file++
-		}
-	}
+// SquareSpot returns the spot that draws sq
+func (b *Board) SquareSpot(sq chess.Square) *Spot {
+	return &b.grid[sq.File()][Size-1-sq.Rank()]
 }
 
-func (b *Board) locationToFileAndRank(loc string) (int, int) {
-	file := int(loc[0] - 'a')
-	rank := 8 - int(loc[1]-'0')
-	fmt.Printf(" file: %v rank: %v", file, rank)
-	return file, rank
+// PieceOnSpot returns the piece on spot in the current position
+func (b *Board) PieceOnSpot(spot *Spot) chess.Piece {
+	return b.position.PieceAt(b.SpotSquare(spot))
 }
 
 // ClearHighlighted sets all highlighted squares back to not highlighted
@@ -129,7 +76,6 @@ func (b *Board) ClearHighlighted() {
 	for rank := 0; rank < Size; rank++ {
 		for file := 0; file < Size; file++ {
 			b.grid[file][rank].highlighted = false
-			b.grid[file][rank].passantTarget--
 		}
 	}
 }
@@ -166,9 +112,16 @@ func (b *Board) ChangeSelectedSpot(fileOff int, rankOff int) {
 
 // PickSpot picks the current selected spot
 func (b *Board) PickSpot() {
+	if !Game.CanMove() {
+		return
+	}
+
+	selectedPiece := b.PieceOnSpot(b.selectedSpot)
+	color := Game.PlayingColor()
+
 	// prevent player from picking spots that don't contain a piece
-	if ((!b.selectedSpot.containsPiece || b.selectedSpot.piece.color != Game.color) && !b.selectedSpot.highlighted) || Game.turn != Game.color {
-		if !b.selectedSpot.containsPiece {
+	if ((selectedPiece.IsEmpty() || selectedPiece.Color != color) && !b.selectedSpot.highlighted) || b.position.Turn != color {
+		if selectedPiece.IsEmpty() {
 			if b.pickedSpot != nil {
 				b.pickedSpot.picked = false
 			}
@@ -185,7 +138,16 @@ func (b *Board) PickSpot() {
 	}
 
 	if b.selectedSpot.highlighted {
-		b.MovePiece(b.pickedSpot, b.selectedSpot)
+		move := chess.Move{From: b.SpotSquare(b.pickedSpot), To: b.SpotSquare(b.selectedSpot)}
+
+		// wait for player to choose a piece before promoting
+		if b.PieceOnSpot(b.pickedSpot).Type == chess.Pawn && (b.selectedSpot.rank == 0 || b.selectedSpot.rank == Size-1) {
+			b.promotionMove = &move
+			b.promotionChoice = 0
+			return
+		}
+
+		b.MovePiece(move)
 		b.pickedSpot = nil
 		return
 	}
@@ -193,147 +155,97 @@ func (b *Board) PickSpot() {
 	b.pickedSpot = b.selectedSpot
 	b.pickedSpot.picked = true
 
-	validMoves, _ := b.pickedSpot.piece.FindValidMoves(b.grid, b.pickedSpot.file, b.pickedSpot.rank, Black, true)
+	validMoves := b.position.LegalMovesFrom(b.SpotSquare(b.pickedSpot))
+	b.ClearHighlighted()
 	b.highlightMoves(validMoves)
 }
 
-func (b *Board) highlightMoves(moves []Spot) {
+func (b *Board) highlightMoves(moves []chess.Move) {
 	for _, m := range moves {
-		b.grid[m.file][m.rank].highlighted = true
+		b.SquareSpot(m.To).highlighted = true
 	}
 }
 
-// IsSpotOffBoard returns true if the spot is not on the board
-func (b *Board) IsSpotOffBoard(file int, rank int) bool {
-	return file < 0 || file > Size-1 || rank < 0 || rank > Size-1
+// IsChoosingPromotion returns true if the player is choosing the piece to promote a pawn to
+func (b *Board) IsChoosingPromotion() bool {
+	return b.promotionMove != nil
 }
 
-// MovePiece moves a piece from a start position on the board to the destination
-func (b *Board) MovePiece(start *Spot, destination *Spot) {
-	turnSuccessful := true
-
-	piece := start.piece
-	destinationPiece := destination.piece
-	piece.moves++
-
-	start.piece = nil
-	start.containsPiece = false
-
-	destination.piece = piece
-	destination.containsPiece = true
-
-	// if it is pawns first move and moved 2 places make spot behind pawn en passant target for next turn
-	if destination.piece.class == Pawn && destination.piece.moves == 1 && destination.rank == 4 {
-		b.grid[destination.file][destination.rank+1].passantTarget = 2
-	}
-
-	// if pawn move results in en passant, take piece behind destination
-	var passantPiece *Piece
-	if destination.passantTarget > 0 {
-		passantSpot := &b.grid[destination.file][start.rank]
-		passantPiece = passantSpot.piece
-		passantSpot.piece = nil
-		passantSpot.containsPiece = false
-	}
+// ChangePromotionChoice moves the promotion choice by offset, wrapping around the available types
+func (b *Board) ChangePromotionChoice(offset int) {
+	count := len(chess.PromotionTypes)
+	b.promotionChoice = ((b.promotionChoice+offset)%count + count) % count
+}
 
-	// if move puts player's king in check then revert the move
-	opponentColor := Black
-	if Game.turn == Black {
-		opponentColor = White
+// ConfirmPromotion plays the waiting promotion move with the chosen piece
+func (b *Board) ConfirmPromotion() {
+	if b.promotionMove == nil {
+		return
 	}
 
-	if b.IsKingInCheck(Game.turn, opponentColor, nil) {
-		piece.moves--
-
-		start.piece = piece
-		start.containsPiece = true
+	move := *b.promotionMove
+	move.Promotion = chess.PromotionTypes[b.promotionChoice]
+	b.promotionMove = nil
 
-		destination.piece = destinationPiece
-		destination.containsPiece = destinationPiece != nil
+	b.MovePiece(move)
+	b.pickedSpot = nil
+}
 
-		if destination.passantTarget > 0 {
-			passantSpot := &b.grid[destination.file][start.rank]
-			passantSpot.piece = passantPiece
-			passantSpot.containsPiece = true
-		}
+// CancelPromotion discards the waiting promotion move
+func (b *Board) CancelPromotion() {
+	b.Unpick()
+}
 
-		turnSuccessful = false
-	}
+// Unpick drops the picked spot, it's highlighted moves and any waiting promotion move
+func (b *Board) Unpick() {
+	b.promotionMove = nil
 
-	// if turn was successfully played then pass turn to opponent
-	if turnSuccessful {
-		Game.NextTurn(Game.turn, opponentColor)
+	if b.pickedSpot != nil {
+		b.pickedSpot.picked = false
 	}
 
-	// clear highlighted possible moves once piece has moved
+	b.pickedSpot = nil
 	b.ClearHighlighted()
 }
 
-// IsKingInCheck goes through each opponent piece on the board and checks if they are attacking
-// color's king
-// returns either true (the king is in check) or false (the king is not in check)
-func (b *Board) IsKingInCheck(color int, opponentColor int, simulatedBoard *[Size][Size]Spot) bool {
-	board := b.grid
-	if simulatedBoard != nil {
-		board = simulatedBoard
+// IsSpotOffBoard returns true if the spot is not on the board
+func (b *Board) IsSpotOffBoard(file int, rank int) bool {
+	return file < 0 || file > Size-1 || rank < 0 || rank > Size-1
+}
+
+// PlayUCI plays the opponent's move written in UCI long algebraic notation, e.g. "e2e4" or "e7e8q"
+// returns an error if the move is malformed or not legal in the current position
+func (b *Board) PlayUCI(uci string) error {
+	move, err := b.position.ParseUCI(uci)
+	if err != nil {
+		return err
 	}
 
-	// check if any opponent's piece puts the king in check
-	for rank := 0; rank < Size; rank++ {
-		for file := 0; file < Size; file++ {
-			if board[file][rank].containsPiece && board[file][rank].piece.color == opponentColor {
-				_, inCheck := board[file][rank].piece.FindValidMoves(board, file, rank, color, false)
-				if inCheck {
-					return true
-				}
-			}
-		}
+	if b.position.IsLegal(move) {
+		b.playMove(move)
 	}
 
-	return false
+	b.ClearHighlighted()
+	return nil
 }
 
-// IsStalemate returns true if color cannot play any moves but is not in check
-func (b *Board) IsStalemate(color int, opponentColor int) bool {
-	king := b.GetKingSpot(color)
-	kingMoves, _ := king.piece.FindValidMoves(b.grid, king.file, king.rank, opponentColor, true)
-
-	// when king cannot move out of check
-	// check if any move by color can get king out of check
-	if len(kingMoves) == 0 {
-		for rank := 0; rank < Size; rank++ {
-			for file := 0; file < Size; file++ {
-				if b.grid[file][rank].containsPiece && b.grid[file][rank].piece.color == color && b.grid[file][rank].piece.class != King {
-					piece := b.grid[file][rank].piece
-					moves, _ := piece.FindValidMoves(b.grid, file, rank, opponentColor, true)
-
-					// since moves are pruned for illegal moves
-					// if any move is available then it will put king out of check
-					if len(moves) > 0 {
-						return false
-					}
-				}
-			}
-		}
-	} else {
-		return false
+// MovePiece plays the player's move on the board if they can move and it is legal
+func (b *Board) MovePiece(move chess.Move) {
+	if Game.CanMove() && b.position.IsLegal(move) {
+		b.playMove(move)
 	}
 
-	return true
+	// clear highlighted possible moves once piece has moved
+	b.ClearHighlighted()
 }
 
-// GetKingSpot returns the spot that contains the king of color color
-func (b *Board) GetKingSpot(color int) *Spot {
-	var king *Spot
-	for rank := 0; rank < Size; rank++ {
-		for file := 0; file < Size; file++ {
-			if b.grid[file][rank].containsPiece && b.grid[file][rank].piece.class == King && b.grid[file][rank].piece.color == color {
-				king = &b.grid[file][rank]
-			}
-		}
-	}
-
-	return king
+// playMove makes move, records it, sends it to the server if it is the player's and passes the turn
+func (b *Board) playMove(move chess.Move) {
+	mover := b.position.Turn
+	undo := b.position.MakeMove(move)
+	Game.RecordMove(undo, mover)
+	Game.SendMove(move, mover)
+	Game.NextTurn()
 }
 
 // ToString returns the board's current state as a single string
@@ -378,7 +290,7 @@ func (b *Board) ToString() string {
 				bgColor = lightSquareColor
 			}
 
-			spot := b.grid[file][rank]
+			spot := &b.grid[file][rank]
 			if spot.picked {
 				if bgColor == darkSquareColor {
 					bgColor = pickedDarkSquareColor
@@ -406,14 +318,13 @@ func (b *Board) ToString() string {
 					margin := bgColor + strings.Repeat(gapChar, spotCols/2)
 					spotStr := " "
 
-					if spot.containsPiece {
-						piece := spot.piece
+					if piece := b.PieceOnSpot(spot); !piece.IsEmpty() {
 						pieceColor := whitePieceColor
-						if piece.color == Black {
+						if piece.Color == chess.Black {
 							pieceColor = blackPieceColor
 						}
 
-						pieceStr := PieceStrings[piece.class]
+						pieceStr := PieceString(piece)
 
 						spotStr = pieceColor + pieceStr
 					}
@@ -461,6 +372,37 @@ func (b *Board) ToString() string {
 
 	output += opponentBand + opponentTimer + playerBand + playerTimer
 
+	// add promotion chooser over the board
+	if b.promotionMove != nil {
+		output += b.createPromotionChooserString(spotCols, spotRows, resetColor)
+	}
+
+	// add the result or takeback prompts over the board
+	if Game.ended {
+		output += b.createPromptString(Game.ResultText(), spotCols, spotRows, resetColor)
+	} else if Game.takebackRequested {
+		output += b.createPromptString(Game.opponent.name+" wants a takeback  y: accept  n: decline", spotCols, spotRows, resetColor)
+	} else if Game.takebackPending {
+		output += b.createPromptString("Takeback requested, waiting for "+Game.opponent.name, spotCols, spotRows, resetColor)
+	} else if Game.drawOffered {
+		output += b.createPromptString(Game.opponent.name+" offers a draw  y: accept  n: decline", spotCols, spotRows, resetColor)
+	} else if Game.drawPending {
+		output += b.createPromptString("Draw offered, waiting for "+Game.opponent.name, spotCols, spotRows, resetColor)
+	} else if Game.disconnected && !Game.ended && Game.reconnected != nil {
+		output += b.createPromptString("Lost connection, reconnecting...", spotCols, spotRows, resetColor)
+	} else if Game.disconnected && !Game.ended {
+		output += b.createPromptString("Lost connection to the server", spotCols, spotRows, resetColor)
+	} else if Game.connection != nil && !Game.opponentConnected && !Game.ended {
+		output += b.createPromptString(Game.opponent.name+" lost connection, waiting for them", spotCols, spotRows, resetColor)
+	}
+
+	// add chat under the board
+	if Game.typingChat {
+		output += fmt.Sprintf("\033[%v;0H\033[Ksay: %s_", spotRows*Size+7, Game.chatDraft)
+	} else if Game.connection != nil {
+		output += fmt.Sprintf("\033[%v;0H\033[K%s", spotRows*Size+7, Game.message)
+	}
+
 	return output
 }
 
@@ -481,9 +423,19 @@ func (b *Board) createUserBandString(user *User, bottom bool, spotCols int, spot
 		}
 
 		if i == textLine {
-			gapCount := cols - len(user.name) - 1
+			text := user.name
+
+			// let the player know they can claim a draw
+			if claim := Game.ClaimableDraw(); !user.opponent && claim != chess.Ongoing && b.position.Turn == Game.PlayingColor() {
+				text += " - d: claim " + claim.String()
+			}
 
-			band += fmt.Sprintf("\033[%v;%vH%s%s %s%s%s", line, 0, bgColor, textColor, user.name, strings.Repeat(" ", gapCount), resetColor)
+			gapCount := cols - len([]rune(text)) - 1
+			if gapCount < 0 {
+				gapCount = 0
+			}
+
+			band += fmt.Sprintf("\033[%v;%vH%s%s %s%s%s", line, 0, bgColor, textColor, text, strings.Repeat(" ", gapCount), resetColor)
 		} else {
 			band += fmt.Sprintf("\033[%v;%vH%s%s%s%s", line, 0, bgColor, textColor, strings.Repeat(" ", cols), resetColor)
 		}
@@ -492,6 +444,8 @@ func (b *Board) createUserBandString(user *User, bottom bool, spotCols int, spot
 	return band
 }
 
+// createTimerString draws a user's timer showing timerMs
+// in networked games it is the time the server last sent counted down locally since it arrived
 func (b *Board) createTimerString(timerMs int, bottom bool, spotCols int, spotRows int, resetColor string) string {
 	timer := ""
 	timerCols := 10
@@ -525,3 +479,75 @@ func (b *Board) createTimerString(timerMs int, bottom bool, spotCols int, spotRo
 
 	return timer
 }
+
+func (b *Board) createPromotionChooserString(spotCols int, spotRows int, resetColor string) string {
+	chooser := ""
+	lines := 3
+	pieceLine := 1
+	bgColor := "\033[48;2;0;0;0m"
+	selectedBgColor := "\033[48;2;109;159;88m"
+	pieceColor := "\033[38;2;255;255;255m"
+	if b.position.PieceAt(b.promotionMove.From).Color == chess.Black {
+		pieceColor = "\033[38;2;0;0;0m"
+		bgColor = "\033[48;2;240;217;181m"
+	}
+
+	// center chooser over the board, the board is drawn from line 4
+	cols := spotCols * len(chess.PromotionTypes)
+	col := (spotCols*Size-cols)/2 + 1
+	line := 4 + (spotRows*Size-lines)/2
+
+	for i := 0; i < lines; i++ {
+		chooser += fmt.Sprintf("\033[%v;%vH", line+i, col)
+
+		for choice, pieceType := range chess.PromotionTypes {
+			optionColor := bgColor
+			if choice == b.promotionChoice {
+				optionColor = selectedBgColor
+			}
+
+			if i == pieceLine {
+				margin := strings.Repeat(" ", spotCols/2)
+				chooser += optionColor + pieceColor + margin + PieceStrings[pieceType] + margin
+			} else {
+				chooser += optionColor + strings.Repeat(" ", spotCols)
+			}
+		}
+
+		chooser += resetColor
+	}
+
+	return chooser
+}
+
+func (b *Board) createPromptString(text string, spotCols int, spotRows int, resetColor string) string {
+	prompt := ""
+	lines := 3
+	textLine := 1
+	bgColor := "\033[48;2;0;0;0m"
+	textColor := "\033[38;2;255;255;255m"
+
+	// center prompt over the board, the board is drawn from line 4
+	cols := spotCols*Size - 2*spotCols
+	line := 4 + (spotRows*Size-lines)/2
+
+	runes := []rune(text)
+	if len(runes) > cols-2 {
+		runes = runes[:cols-2]
+	}
+
+	for i := 0; i < lines; i++ {
+		prompt += fmt.Sprintf("\033[%v;%vH%s%s", line+i, spotCols+1, bgColor, textColor)
+
+		if i == textLine {
+			gap := cols - len(runes)
+			prompt += strings.Repeat(" ", gap/2) + string(runes) + strings.Repeat(" ", gap-gap/2)
+		} else {
+			prompt += strings.Repeat(" ", cols)
+		}
+
+		prompt += resetColor
+	}
+
+	return prompt
+}
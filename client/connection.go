@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
+)
+
+// dialTimeout is how long to wait for the server to accept a connection
+const dialTimeout = 5 * time.Second
+
+// ServerConnection is a connection to the game server
+// messages are received in the background and handled by the current level on it's tick
+type ServerConnection struct {
+	conn     *protocol.Conn
+	messages chan protocol.Message
+
+	// err is set once the connection has been lost
+	err error
+}
+
+// Dial connects to the game server at addr and says hello as name
+// the server's welcome or rejection of our protocol version arrives as a message
+func Dial(addr string, name string) (*ServerConnection, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ServerConnection{
+		conn:     protocol.NewConn(conn),
+		messages: make(chan protocol.Message, 64),
+	}
+
+	if err := c.conn.Send(&protocol.Hello{Version: protocol.Version, Name: name}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.receive()
+
+	return c, nil
+}
+
+// Reconnect connects to the game server at addr again and asks to resume the game token was given for
+func Reconnect(addr string, name string, token string) (*ServerConnection, error) {
+	c, err := Dial(addr, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.conn.Send(&protocol.Resume{Token: token}); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// receive reads messages from the server until the connection is lost
+// messages that can't be decoded are skipped and pings are answered
+func (c *ServerConnection) receive() {
+	for {
+		msg, err := c.conn.Receive()
+
+		var decodeErr *protocol.DecodeError
+		if errors.As(err, &decodeErr) {
+			continue
+		} else if err != nil {
+			c.err = err
+			close(c.messages)
+			return
+		}
+
+		// pings are answered straight away so the server measures the network and not our frame rate
+		if ping, ok := msg.(*protocol.Ping); ok {
+			c.conn.Send(&protocol.Pong{ID: ping.ID})
+			continue
+		}
+
+		c.messages <- msg
+	}
+}
+
+// Poll returns the next message from the server without waiting
+// ok is false if there is no message, closed is true once the connection has been lost
+func (c *ServerConnection) Poll() (msg protocol.Message, ok bool, closed bool) {
+	select {
+	case msg, open := <-c.messages:
+		return msg, open, !open
+	default:
+		return nil, false, false
+	}
+}
+
+// Err returns why the connection was lost
+func (c *ServerConnection) Err() error {
+	return c.err
+}
+
+// Close disconnects from the server
+func (c *ServerConnection) Close() error {
+	return c.conn.Close()
+}
+
+// CreateRoom asks the server for a new room playing from fen, or the starting position if it is empty
+func (c *ServerConnection) CreateRoom(control chess.TimeControl, fen string) error {
+	return c.conn.Send(&protocol.CreateRoom{TimeControl: control.String(), FEN: fen})
+}
+
+// JoinRoom asks the server to join the room with code
+func (c *ServerConnection) JoinRoom(code string) error {
+	return c.conn.Send(&protocol.JoinRoom{Code: code})
+}
+
+// SendMove sends the player's move to the server
+func (c *ServerConnection) SendMove(move chess.Move) error {
+	return c.conn.Send(&protocol.Move{Move: move.UCI()})
+}
+
+// RequestTakeback asks the opponent to let the player take back their last move
+func (c *ServerConnection) RequestTakeback() error {
+	return c.conn.Send(&protocol.TakebackRequest{})
+}
+
+// RespondTakeback answers the opponent's takeback request
+func (c *ServerConnection) RespondTakeback(accept bool) error {
+	return c.conn.Send(&protocol.TakebackAnswer{Accept: accept})
+}
+
+// OfferDraw offers the opponent a draw or claims one if the player can
+func (c *ServerConnection) OfferDraw() error {
+	return c.conn.Send(&protocol.DrawOffer{})
+}
+
+// RespondDraw answers the opponent's draw offer
+func (c *ServerConnection) RespondDraw(accept bool) error {
+	return c.conn.Send(&protocol.DrawAnswer{Accept: accept})
+}
+
+// Resign resigns the game
+func (c *ServerConnection) Resign() error {
+	return c.conn.Send(&protocol.Resign{})
+}
+
+// SendChat sends a chat message to the opponent
+func (c *ServerConnection) SendChat(text string) error {
+	return c.conn.Send(&protocol.Chat{Text: text})
+}
@@ -1,54 +1,807 @@
 package main
 
-// CastlingRights stores what side a player can castle
-type CastlingRights struct {
-	queenside bool
-	kingside  bool
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
+)
+
+// Connection sends game events to the opponent in a networked game
+type Connection interface {
+	// Poll returns the next message from the server without waiting
+	// ok is false if there is no message, closed is true once the connection has been lost
+	Poll() (msg protocol.Message, ok bool, closed bool)
+
+	// SendMove sends the player's move
+	SendMove(move chess.Move) error
+
+	// RequestTakeback asks the opponent to let the player take back their last move
+	RequestTakeback() error
+
+	// RespondTakeback answers the opponent's takeback request
+	RespondTakeback(accept bool) error
+
+	// OfferDraw offers the opponent a draw or claims one if the player can
+	OfferDraw() error
+
+	// RespondDraw answers the opponent's draw offer
+	RespondDraw(accept bool) error
+
+	// Resign resigns the game
+	Resign() error
+
+	// SendChat sends a chat message to the opponent
+	SendChat(text string) error
+}
+
+// reconnectInterval is how long to wait between attempts to reconnect to the server
+const reconnectInterval = 2 * time.Second
+
+// reconnectAttempts is how many times to try reconnecting before giving up
+const reconnectAttempts = 30
+
+// unknownClock is the clock of a played move whose time isn't known
+const unknownClock = -1
+
+// playedMove is a move in the game's history
+type playedMove struct {
+	undo chess.Undo
+
+	// clock of the player who made the move once it was made, unknownClock for moves replayed after resuming
+	clock int
+
+	// both clocks before the move was made, restored when it is taken back
+	youTimeBefore      int
+	opponentTimeBefore int
 }
 
 // GameController controls top level game logic and handles server connections
 type GameController struct {
-	color         int
-	opponentColor int
+	color         chess.Color
+	opponentColor chess.Color
 	board         *Board
 	ended         bool
-	endState      string
-	turn          int
-	halfmoves     int
-	fullmoves     int
+	endState      chess.Status
 
-	whiteCastling *CastlingRights
-	blackCastling *CastlingRights
+	// player who was checkmated, resigned or ran out of time
+	loser chess.Color
 
 	you      *User
 	opponent *User
 
 	timeOfLastTick int
 	deltaTime      int
+
+	// clock the users' times are kept by, nil in untimed games
+	clock *chess.Clock
+
+	// move history from the start position
+	startPosition chess.Position
+	startTime     time.Time
+	history       []playedMove
+
+	// number of times each position has been reached, keyed by it's hash
+	repetitions map[uint64]int
+
+	// directory finished games are saved to as PGN
+	pgnDir string
+
+	// connection to the opponent, nil in local games
+	connection        Connection
+	disconnected      bool
+	opponentConnected bool
+
+	// token to resume the game with after losing the connection,
+	// where the new connection arrives while reconnecting, nil when not reconnecting,
+	// and whether the server has yet to answer the resume request sent on it
+	resumeToken string
+	reconnected chan Connection
+	resuming    bool
+
+	// whether the last move in the history is the player's and the server has yet to echo it back
+	unconfirmed bool
+
+	// takeback the opponent has asked for and one the player is waiting on an answer to
+	takebackRequested bool
+	takebackPending   bool
+
+	// draw the opponent has offered and one the player is waiting on an answer to
+	drawOffered bool
+	drawPending bool
+
+	// last chat message or error from the server and the chat message being typed
+	message    string
+	typingChat bool
+	chatDraft  string
+}
+
+// ResetHistory starts recording a new game from the board's current position
+func (g *GameController) ResetHistory() {
+	g.startPosition = *g.board.position
+	g.startTime = time.Now()
+	g.history = nil
+	g.repetitions = map[uint64]int{g.board.position.Hash(): 1}
+}
+
+// RecordMove adds the move made with undo to the history and presses mover's clock
+func (g *GameController) RecordMove(undo chess.Undo, mover chess.Color) {
+	played := playedMove{
+		undo:               undo,
+		youTimeBefore:      g.you.time,
+		opponentTimeBefore: g.opponent.time,
+	}
+
+	if g.clock != nil {
+		g.clock.Press(mover)
+		g.syncTimes()
+	}
+
+	played.clock = g.UserOf(mover).time
+	g.addToHistory(played)
+}
+
+// replayMove adds a move made before the game was resumed to the history without touching the clocks
+func (g *GameController) replayMove(undo chess.Undo) {
+	g.addToHistory(playedMove{
+		undo:               undo,
+		clock:              unknownClock,
+		youTimeBefore:      g.you.time,
+		opponentTimeBefore: g.opponent.time,
+	})
+}
+
+// addToHistory appends played to the history and counts the position it reached
+func (g *GameController) addToHistory(played playedMove) {
+	g.history = append(g.history, played)
+	g.repetitions[g.board.position.Hash()]++
+}
+
+// UndoMove takes back the last move played and reopens the game if it had ended
+// returns false if there are no moves to take back
+func (g *GameController) UndoMove() bool {
+	if len(g.history) == 0 {
+		return false
+	}
+
+	last := len(g.history) - 1
+	g.repetitions[g.board.position.Hash()]--
+	g.board.position.UnmakeMove(g.history[last].undo)
+
+	g.history = g.history[:last]
+	g.ended = false
+	g.endState = chess.Ongoing
+
+	g.board.Unpick()
+
+	return true
+}
+
+// TakeBack takes back moves until it is requester's turn again
+// and restores the clocks to what they were before requester's move
+func (g *GameController) TakeBack(requester chess.Color) bool {
+	plies := g.board.position.TakebackPlies(requester, len(g.history))
+	if plies == 0 {
+		return false
+	}
+
+	first := g.history[len(g.history)-plies]
+	for i := 0; i < plies; i++ {
+		g.UndoMove()
+	}
+
+	g.restoreClocks(first)
+
+	return true
+}
+
+// restoreClocks sets the clocks back to what they were before played was made
+func (g *GameController) restoreClocks(played playedMove) {
+	g.you.time = played.youTimeBefore
+	g.opponent.time = played.opponentTimeBefore
+
+	if g.clock != nil {
+		g.clock.SetRemaining(g.color, time.Duration(g.you.time)*time.Millisecond, g.movesMade(g.color))
+		g.clock.SetRemaining(g.opponentColor, time.Duration(g.opponent.time)*time.Millisecond, g.movesMade(g.opponentColor))
+	}
+}
+
+// undoUnconfirmed takes back the player's move the server refused or never played
+func (g *GameController) undoUnconfirmed() {
+	if !g.unconfirmed {
+		return
+	}
+
+	g.unconfirmed = false
+
+	played := g.history[len(g.history)-1]
+	g.UndoMove()
+	g.restoreClocks(played)
+}
+
+// StartPracticeGame sets up a game on this computer from StartFEN where the player moves both sides
+func (g *GameController) StartPracticeGame() error {
+	fen := StartFEN
+	if fen == "" {
+		fen = chess.StartingFEN
+	}
+
+	position, err := chess.ParseFEN(fen)
+	if err != nil {
+		return err
+	}
+
+	if err := g.setupGame(position.Turn, g.you.name, fen, g.clock.Control); err != nil {
+		return err
+	}
+
+	g.connection = nil
+	g.resumeToken = ""
+
+	return nil
+}
+
+// StartNetworkGame sets up a game played over connection as described by start
+func (g *GameController) StartNetworkGame(connection Connection, start *protocol.GameStart) error {
+	if err := g.setupNetworkGame(start.Color, start.Opponent, start.FEN, start.TimeControl); err != nil {
+		return err
+	}
+
+	g.connection = connection
+	g.resumeToken = start.Token
+
+	return nil
+}
+
+// resumeGame rebuilds the game from the state the server sent once the player reconnected
+func (g *GameController) resumeGame(state *protocol.GameState) error {
+	if err := g.setupNetworkGame(state.Color, state.Opponent, state.StartFEN, state.TimeControl); err != nil {
+		return err
+	}
+
+	for _, uci := range state.Moves {
+		move, err := g.board.position.ParseUCI(uci)
+		if err != nil {
+			return err
+		}
+
+		g.replayMove(g.board.position.MakeMove(move))
+	}
+
+	// the clocks are the server's as the time each move was made at isn't known
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		g.clock.SetRemaining(color, g.clock.Remaining(color), g.movesMade(color))
+	}
+
+	g.resumeToken = state.Token
+	g.opponentConnected = state.OpponentConnected
+	g.setClocks(state.WhiteClock, state.BlackClock)
+
+	return nil
+}
+
+// setupNetworkGame starts a new game from fen with the player as color as the server described it
+func (g *GameController) setupNetworkGame(color string, opponent string, fen string, timeControl string) error {
+	c, err := protocol.ParseColor(color)
+	if err != nil {
+		return err
+	}
+
+	control, err := chess.ParseTimeControl(timeControl)
+	if err != nil {
+		return err
+	}
+
+	return g.setupGame(c, opponent, fen, control)
+}
+
+// setupGame starts a new game from fen with the player as color
+func (g *GameController) setupGame(c chess.Color, opponent string, fen string, control chess.TimeControl) error {
+	if err := g.board.GenerateFromFENString(fen); err != nil {
+		return err
+	}
+
+	g.board.Unpick()
+
+	g.color = c
+	g.opponentColor = c.Opponent()
+	g.opponent.name = opponent
+	g.opponentConnected = true
+	g.disconnected = false
+	g.ended = false
+	g.endState = chess.Ongoing
+	g.unconfirmed = false
+	g.takebackRequested = false
+	g.takebackPending = false
+	g.drawOffered = false
+	g.drawPending = false
+	g.message = ""
+	g.typingChat = false
+	g.timeOfLastTick = 0
+
+	g.StartClock(control)
+	g.ResetHistory()
+
+	return nil
+}
+
+// SendMove sends a move the player made to the server in networked games
+func (g *GameController) SendMove(move chess.Move, mover chess.Color) {
+	if g.connection == nil || mover != g.color {
+		return
+	}
+
+	// a lost connection is noticed when polling for messages
+	if err := g.connection.SendMove(move); err == nil {
+		g.unconfirmed = true
+	}
+}
+
+// HandleMessages handles every message that has arrived from the server
+// and starts reconnecting if the connection has been lost
+func (g *GameController) HandleMessages() {
+	if g.reconnected != nil {
+		select {
+		case connection, ok := <-g.reconnected:
+			g.reconnected = nil
+			if ok {
+				g.connection = connection
+				g.disconnected = false
+				g.resuming = true
+			} else {
+				// gave up reconnecting, the result is never going to arrive
+				g.message = "could not reconnect to the server"
+				g.ended = true
+			}
+		default:
+		}
+	}
+
+	if g.connection == nil || g.disconnected {
+		return
+	}
+
+	for {
+		msg, ok, closed := g.connection.Poll()
+		if closed {
+			g.disconnected = true
+			if !g.ended && g.resumeToken != "" {
+				g.reconnect()
+			}
+
+			return
+		} else if !ok {
+			return
+		}
+
+		g.handleMessage(msg)
+	}
+}
+
+// reconnect tries to reconnect to the server and resume the game in the background
+// the new connection is picked up by HandleMessages and the server then sends the game state
+func (g *GameController) reconnect() {
+	reconnected := make(chan Connection, 1)
+	g.reconnected = reconnected
+
+	go func(addr string, name string, token string) {
+		for i := 0; i < reconnectAttempts; i++ {
+			connection, err := Reconnect(addr, name, token)
+			if err == nil {
+				reconnected <- connection
+				return
+			}
+
+			time.Sleep(reconnectInterval)
+		}
+
+		close(reconnected)
+	}(ServerAddr, g.you.name, g.resumeToken)
+}
+
+// handleMessage updates the game with a message from the server
+func (g *GameController) handleMessage(msg protocol.Message) {
+	switch msg := msg.(type) {
+	case *protocol.MoveMade:
+		// the player's own moves have already been played and are echoed back with the server's clocks
+		if g.unconfirmed && g.isOwnLastMove(msg.Move) {
+			g.unconfirmed = false
+		} else if err := g.board.PlayUCI(msg.Move); err != nil {
+			return
+		}
+
+		// the server cancels takeback requests and draw offers once a move is made
+		g.takebackRequested = false
+		g.takebackPending = false
+		g.drawOffered = false
+		g.drawPending = false
+
+		g.setClocks(msg.WhiteClock, msg.BlackClock)
+	case *protocol.ClockSync:
+		g.setClocks(msg.WhiteClock, msg.BlackClock)
+	case *protocol.TakebackRequest:
+		g.OnTakebackRequested()
+	case *protocol.TakebackAnswer:
+		g.OnTakebackAnswered(msg.Accept)
+	case *protocol.DrawOffer:
+		g.drawOffered = true
+	case *protocol.DrawAnswer:
+		g.drawPending = false
+	case *protocol.GameState:
+		g.resuming = false
+		if err := g.resumeGame(msg); err != nil {
+			g.message = "could not resume game: " + err.Error()
+		}
+	case *protocol.OpponentStatus:
+		g.opponentConnected = msg.Connected
+	case *protocol.Chat:
+		g.message = msg.From + ": " + msg.Text
+	case *protocol.Error:
+		g.message = "server: " + msg.Message
+
+		// replies come in order so an error before the echo means the move was refused
+		g.undoUnconfirmed()
+
+		// the game is gone, usually because the player came back long after it ended
+		if g.resuming {
+			g.resuming = false
+			g.resumeToken = ""
+			g.ended = true
+		}
+	case *protocol.GameOver:
+		g.resuming = false
+
+		// the game ended before the server got the player's move, such as when they were flagged
+		g.undoUnconfirmed()
+
+		if g.ended {
+			return
+		}
+
+		status, err := chess.ParseStatus(msg.Reason)
+		if err != nil {
+			return
+		}
+
+		loser, _ := protocol.ParseColor(msg.Loser)
+		g.drawOffered = false
+		g.drawPending = false
+		g.End(status, loser)
+	}
+}
+
+// isOwnLastMove returns true if uci is the last move in the history and the player made it
+func (g *GameController) isOwnLastMove(uci string) bool {
+	if len(g.history) == 0 || g.board.position.Turn != g.opponentColor {
+		return false
+	}
+
+	return g.history[len(g.history)-1].undo.Move.UCI() == uci
+}
+
+// setClocks sets both clocks to the times the server sent in milliseconds
+// the player to move's clock then counts down locally until the server sends the next times
+func (g *GameController) setClocks(whiteMs int, blackMs int) {
+	if g.clock == nil {
+		return
+	}
+
+	g.clock.SetRemaining(chess.White, time.Duration(whiteMs)*time.Millisecond, g.clock.Moves(chess.White))
+	g.clock.SetRemaining(chess.Black, time.Duration(blackMs)*time.Millisecond, g.clock.Moves(chess.Black))
+	g.syncTimes()
+}
+
+// OfferDraw offers the opponent a draw in networked games
+func (g *GameController) OfferDraw() {
+	if g.connection == nil || g.ended || g.drawPending {
+		return
+	}
+
+	if err := g.connection.OfferDraw(); err == nil {
+		g.drawPending = true
+	}
+}
+
+// AnswerDraw accepts or declines the opponent's draw offer, the server ends the game if it is accepted
+func (g *GameController) AnswerDraw(accept bool) {
+	if !g.drawOffered {
+		return
+	}
+
+	g.drawOffered = false
+	g.connection.RespondDraw(accept)
+}
+
+// Resign ends the game with the player losing
+func (g *GameController) Resign() {
+	if g.ended {
+		return
+	}
+
+	// networked games end when the server says so
+	if g.connection != nil {
+		g.connection.Resign()
+		return
+	}
+
+	g.End(chess.Resignation, g.PlayingColor())
 }
 
-// NextTurn performs end game state checks and if game does not end then proceeds to next turn
-func (g *GameController) NextTurn(color int, opponentColor int) {
-	// check for winning conditions
-	if g.board.IsStalemate(opponentColor, color) {
-		Game.ended = true
+// StartChat starts typing a chat message in networked games
+func (g *GameController) StartChat() {
+	if g.connection == nil || g.disconnected {
+		return
+	}
+
+	g.typingChat = true
+	g.chatDraft = ""
+}
+
+// TypeChat adds ch to the chat message being typed
+func (g *GameController) TypeChat(ch rune) {
+	if ch != 0 && len([]rune(g.chatDraft)) < protocol.MaxChatLength {
+		g.chatDraft += string(ch)
+	}
+}
+
+// DeleteChat removes the last character of the chat message being typed
+func (g *GameController) DeleteChat() {
+	if runes := []rune(g.chatDraft); len(runes) > 0 {
+		g.chatDraft = string(runes[:len(runes)-1])
+	}
+}
+
+// SendChat sends the chat message that was typed to the opponent
+func (g *GameController) SendChat() {
+	g.typingChat = false
+	if g.chatDraft != "" && g.connection.SendChat(g.chatDraft) == nil {
+		g.message = g.you.name + ": " + g.chatDraft
+	}
+}
+
+// movesMade returns the number of moves color has made in the history
+func (g *GameController) movesMade(color chess.Color) int {
+	moves := len(g.history) / 2
+	if len(g.history)%2 == 1 && g.startPosition.Turn == color {
+		moves++
+	}
+
+	return moves
+}
 
-		if g.board.IsKingInCheck(opponentColor, color, nil) {
-			Game.endState = "checkmate"
-		} else {
-			Game.endState = "stalemate"
+// RequestTakeback asks to take back the player's last move
+// practice games take back the last move straight away while networked games wait for the opponent to answer
+func (g *GameController) RequestTakeback() {
+	if g.connection == nil {
+		g.TakeBack(g.PlayingColor().Opponent())
+		return
+	}
+
+	if g.ended || g.takebackPending || g.board.position.TakebackPlies(g.color, len(g.history)) == 0 {
+		return
+	}
+
+	if err := g.connection.RequestTakeback(); err == nil {
+		g.takebackPending = true
+	}
+}
+
+// AnswerTakeback accepts or declines the opponent's takeback request
+// an accepted takeback is made once the server confirms it
+func (g *GameController) AnswerTakeback(accept bool) {
+	if !g.takebackRequested {
+		return
+	}
+
+	g.takebackRequested = false
+	g.connection.RespondTakeback(accept)
+}
+
+// OnTakebackRequested is called when the opponent asks to take back their last move
+func (g *GameController) OnTakebackRequested() {
+	if g.board.position.TakebackPlies(g.opponentColor, len(g.history)) > 0 {
+		g.takebackRequested = true
+	}
+}
+
+// OnTakebackAnswered is called when the server confirms the answer to a takeback request
+// accepted answers are sent to both players so it takes back the requester's move, whoever asked
+func (g *GameController) OnTakebackAnswered(accepted bool) {
+	if g.takebackPending {
+		g.takebackPending = false
+		if accepted {
+			g.TakeBack(g.color)
 		}
+	} else if accepted {
+		g.TakeBack(g.opponentColor)
+	}
+}
+
+// StartClock gives both users the time of control and starts timing the game
+func (g *GameController) StartClock(control chess.TimeControl) {
+	g.clock = chess.NewClock(control)
+	g.syncTimes()
+}
+
+// TickClock takes elapsedMs off the clock of the player to move
+// and ends the game if they run out of time, networked games wait for the server to flag them
+func (g *GameController) TickClock(elapsedMs int) {
+	if g.clock == nil || g.ended {
+		return
+	}
+
+	turn := g.board.position.Turn
+	g.clock.Tick(turn, time.Duration(elapsedMs)*time.Millisecond)
+	g.syncTimes()
+
+	if g.clock.Flagged(turn) && g.connection == nil {
+		g.End(g.board.position.TimeoutStatus(turn), turn)
+	}
+}
+
+// syncTimes copies the time each user has left from the clock
+func (g *GameController) syncTimes() {
+	g.you.time = int(g.clock.Remaining(g.color).Milliseconds())
+	g.opponent.time = int(g.clock.Remaining(g.opponentColor).Milliseconds())
+}
+
+// CanMove returns true if the player can make a move
+// no moves can be made once the game is over, while the server can't be reached
+// or while waiting on a takeback, as the server might take back moves before the new one arrives
+func (g *GameController) CanMove() bool {
+	return !g.ended && !g.disconnected && !g.takebackPending
+}
+
+// PlayingColor returns the color the player is moving, in practice games they play whichever side is to move
+func (g *GameController) PlayingColor() chess.Color {
+	if g.connection == nil {
+		return g.board.position.Turn
+	}
+
+	return g.color
+}
+
+// UserOf returns the user playing color
+func (g *GameController) UserOf(color chess.Color) *User {
+	if color == g.color {
+		return g.you
+	}
+
+	return g.opponent
+}
+
+// NextTurn performs end game state checks after a move has been played
+func (g *GameController) NextTurn() {
+	if status := g.Status(); status != chess.Ongoing {
+		g.End(status, g.board.position.Turn)
+	}
+}
+
+// Status returns the status of the game
+// once the game has ended it is the reason it ended, otherwise it is decided by the current position
+func (g *GameController) Status() chess.Status {
+	if g.ended {
+		return g.endState
+	}
+
+	position := g.board.position
+	return position.Status(g.repetitions[position.Hash()])
+}
+
+// End ends the game because of endState and saves it
+// loser is the player who was checkmated, resigned or ran out of time and is ignored for draws
+func (g *GameController) End(endState chess.Status, loser chess.Color) {
+	g.ended = true
+	g.endState = endState
+	g.loser = loser
+
+	// there is nowhere to report an error during the game so a failed save is dropped
+	g.SavePGN()
+}
+
+// ClaimableDraw returns the draw the player to move can claim or chess.Ongoing if there is none
+func (g *GameController) ClaimableDraw() chess.Status {
+	if g.ended {
+		return chess.Ongoing
+	}
+
+	position := g.board.position
+	return position.ClaimableDraw(g.repetitions[position.Hash()])
+}
+
+// ClaimDraw ends the game as a draw if the player can claim one on their turn
+func (g *GameController) ClaimDraw() {
+	if g.board.position.Turn != g.PlayingColor() {
+		return
+	}
+
+	claim := g.ClaimableDraw()
+	if claim == chess.Ongoing {
+		return
+	}
+
+	// the server checks the claim in networked games and ends the game
+	if g.connection != nil {
+		g.connection.OfferDraw()
+		return
+	}
+
+	g.End(claim, g.color)
+}
+
+// Result returns the result of the game as written in PGN
+func (g *GameController) Result() string {
+	return g.Status().Result(g.loser)
+}
+
+// ResultText returns who won the game and why, for showing once it has ended
+func (g *GameController) ResultText() string {
+	status := g.Status()
+	if status == chess.Ongoing {
+		return "Game over, the result is unknown"
+	}
+
+	if status.IsDraw() {
+		return "Draw: " + status.String()
+	}
+
+	return g.UserOf(g.loser.Opponent()).name + " won by " + status.String()
+}
+
+// PGN returns the game played so far as PGN
+func (g *GameController) PGN() *chess.PGN {
+	pgn := chess.NewPGN()
+	pgn.SetTag("Event", "Casual game")
+	pgn.SetTag("Date", g.startTime.Format("2006.01.02"))
+	pgn.SetTag("Round", "-")
+	pgn.SetTag("White", g.UserOf(chess.White).name)
+	pgn.SetTag("Black", g.UserOf(chess.Black).name)
+	pgn.Result = g.Result()
+
+	if g.clock != nil {
+		pgn.SetTag("TimeControl", g.clock.Control.PGNTag())
 	}
 
-	Game.halfmoves++
-	if Game.turn == Black {
-		Game.fullmoves++
-		Game.turn = White
-	} else {
-		Game.turn = Black
+	if g.startPosition.FEN() != chess.StartingFEN {
+		start := g.startPosition
+		pgn.Start = &start
+	}
+
+	for _, played := range g.history {
+		comment := ""
+		if played.clock != unknownClock {
+			comment = chess.ClockComment(played.clock)
+		}
+
+		pgn.AddMove(played.undo.Move, comment)
 	}
 
-	if Game.fullmoves == 50 {
-		Game.ended = true
+	return pgn
+}
+
+// SavePGN writes the game to a new file in the PGN directory and returns it's path
+func (g *GameController) SavePGN() (string, error) {
+	if err := os.MkdirAll(g.pgnDir, 0755); err != nil {
+		return "", err
 	}
+
+	name := fmt.Sprintf("%s_%s_vs_%s.pgn", g.startTime.Format("2006-01-02_150405"), fileSafeName(g.UserOf(chess.White).name), fileSafeName(g.UserOf(chess.Black).name))
+	path := filepath.Join(g.pgnDir, name)
+
+	return path, os.WriteFile(path, []byte(g.PGN().String()), 0644)
+}
+
+// fileSafeName replaces characters that can't be used in file names
+func fileSafeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == ':' {
+			return '_'
+		}
+
+		return r
+	}, name)
 }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
+)
+
+// newTestGame returns a practice game from the starting position with a 5+3 clock
+func newTestGame(t *testing.T) *GameController {
+	t.Helper()
+
+	board := &Board{}
+	board.Setup()
+
+	g := &GameController{
+		board:         board,
+		color:         chess.White,
+		opponentColor: chess.Black,
+		you:           &User{"white", 0, false},
+		opponent:      &User{"black", 0, true},
+	}
+
+	control, err := chess.ParseTimeControl("5+3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.StartClock(control)
+	g.ResetHistory()
+
+	return g
+}
+
+// play spends elapsedMs thinking then plays uci
+func play(t *testing.T, g *GameController, elapsedMs int, uci string) {
+	t.Helper()
+
+	g.TickClock(elapsedMs)
+
+	move, err := g.board.position.ParseUCI(uci)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mover := g.board.position.Turn
+	g.RecordMove(g.board.position.MakeMove(move), mover)
+}
+
+// checkClock checks color's clock shows remaining after moves moves
+func checkClock(t *testing.T, g *GameController, color chess.Color, remaining time.Duration, moves int) {
+	t.Helper()
+
+	if got := g.clock.Remaining(color); got != remaining {
+		t.Errorf("%s has %v left, want %v", color, got, remaining)
+	}
+
+	if got := g.clock.Moves(color); got != moves {
+		t.Errorf("%s has made %d moves on the clock, want %d", color, got, moves)
+	}
+
+	if got := g.UserOf(color).time; got != int(remaining.Milliseconds()) {
+		t.Errorf("%s's timer shows %dms, want %dms", color, got, remaining.Milliseconds())
+	}
+}
+
+func TestTakeBackOnOpponentsTurn(t *testing.T) {
+	g := newTestGame(t)
+
+	play(t, g, 1000, "e2e4")
+	play(t, g, 2000, "e7e5")
+	play(t, g, 4000, "g1f3")
+
+	if !g.TakeBack(chess.White) {
+		t.Fatal("TakeBack failed")
+	}
+
+	if len(g.history) != 2 || g.board.position.Turn != chess.White {
+		t.Fatalf("%d moves left with %s to move, want 2 with white to move", len(g.history), g.board.position.Turn)
+	}
+
+	// white keeps the time spent on Nf3 but not its increment
+	checkClock(t, g, chess.White, 5*time.Minute-2*time.Second, 1)
+	checkClock(t, g, chess.Black, 5*time.Minute+time.Second, 1)
+}
+
+func TestTakeBackOnOwnTurn(t *testing.T) {
+	g := newTestGame(t)
+
+	play(t, g, 1000, "e2e4")
+	play(t, g, 2000, "e7e5")
+	g.TickClock(3000)
+
+	if !g.TakeBack(chess.White) {
+		t.Fatal("TakeBack failed")
+	}
+
+	if len(g.history) != 0 || g.board.position.FEN() != chess.StartingFEN {
+		t.Fatalf("position after the takeback = %q, want the starting position", g.board.position.FEN())
+	}
+
+	checkClock(t, g, chess.White, 5*time.Minute-time.Second, 0)
+	checkClock(t, g, chess.Black, 5*time.Minute, 0)
+}
+
+func TestTakeBackWithoutMoves(t *testing.T) {
+	g := newTestGame(t)
+
+	if g.TakeBack(chess.White) {
+		t.Error("TakeBack succeeded before any moves were made")
+	}
+}
+
+func TestResumeGameUsesTheServersClocks(t *testing.T) {
+	g := newTestGame(t)
+
+	err := g.resumeGame(&protocol.GameState{
+		Code:        "ABCDEF",
+		Color:       "black",
+		Opponent:    "white",
+		StartFEN:    chess.StartingFEN,
+		Moves:       []string{"e2e4", "e7e5", "g1f3"},
+		TimeControl: "5+3",
+		WhiteClock:  290000,
+		BlackClock:  280000,
+		Token:       "token",
+	})
+	if err != nil {
+		t.Fatalf("resumeGame: %v", err)
+	}
+
+	if len(g.history) != 3 || g.board.position.Turn != chess.Black {
+		t.Fatalf("%d moves with %s to move, want 3 with black to move", len(g.history), g.board.position.Turn)
+	}
+
+	checkClock(t, g, chess.White, 290*time.Second, 2)
+	checkClock(t, g, chess.Black, 280*time.Second, 1)
+
+	// the times the replayed moves were made at aren't known
+	for i, comment := range g.PGN().Comments {
+		if comment != "" {
+			t.Errorf("move %d has clock comment %q, want none", i+1, comment)
+		}
+	}
+}
+
+// testConnection is a connection to a server that accepts everything sent to it
+type testConnection struct{}
+
+func (testConnection) Poll() (protocol.Message, bool, bool) { return nil, false, false }
+func (testConnection) SendMove(move chess.Move) error       { return nil }
+func (testConnection) RequestTakeback() error               { return nil }
+func (testConnection) RespondTakeback(accept bool) error    { return nil }
+func (testConnection) OfferDraw() error                     { return nil }
+func (testConnection) RespondDraw(accept bool) error        { return nil }
+func (testConnection) Resign() error                        { return nil }
+func (testConnection) SendChat(text string) error           { return nil }
+
+// sendMove plays uci as the player after elapsedMs and sends it to the server
+func sendMove(t *testing.T, g *GameController, elapsedMs int, uci string) {
+	t.Helper()
+
+	play(t, g, elapsedMs, uci)
+	g.SendMove(g.history[len(g.history)-1].undo.Move, g.color)
+}
+
+func TestConfirmedMovesAreKept(t *testing.T) {
+	g := newTestGame(t)
+	g.connection = testConnection{}
+
+	sendMove(t, g, 1000, "e2e4")
+	g.handleMessage(&protocol.MoveMade{Move: "e2e4", WhiteClock: 301500, BlackClock: 300000})
+
+	if len(g.history) != 1 || g.unconfirmed {
+		t.Fatalf("%d moves, unconfirmed %v after the echo, want 1 confirmed move", len(g.history), g.unconfirmed)
+	}
+
+	checkClock(t, g, chess.White, 301500*time.Millisecond, 1)
+}
+
+func TestRefusedMovesAreTakenBack(t *testing.T) {
+	g := newTestGame(t)
+	g.connection = testConnection{}
+
+	sendMove(t, g, 1000, "e2e4")
+	g.handleMessage(&protocol.Error{Code: protocol.ErrRejected, Message: "not your turn"})
+
+	if len(g.history) != 0 || g.board.position.FEN() != chess.StartingFEN {
+		t.Fatalf("position after the refused move = %q, want the starting position", g.board.position.FEN())
+	}
+
+	checkClock(t, g, chess.White, 5*time.Minute-time.Second, 0)
+}
+
+func TestMovesAfterFlagFallAreTakenBack(t *testing.T) {
+	g := newTestGame(t)
+	g.connection = testConnection{}
+	g.pgnDir = t.TempDir()
+
+	sendMove(t, g, 1000, "e2e4")
+	g.handleMessage(&protocol.GameOver{Result: chess.BlackWins, Reason: "timeout", Loser: "white"})
+
+	if len(g.history) != 0 || !g.ended || g.endState != chess.Timeout {
+		t.Fatalf("%d moves, ended %v by %v, want no moves and a timeout", len(g.history), g.ended, g.endState)
+	}
+}
@@ -4,5 +4,8 @@ go 1.16
 
 require (
 	github.com/JoelOtter/termloop v0.0.0-20201118115657-7fa23b4da654 // direct
+	github.com/freddie-nelson/chess/chess v0.0.0
 	github.com/nsf/termbox-go v1.1.0 // indirect
 )
+
+replace github.com/freddie-nelson/chess/chess => ../chess
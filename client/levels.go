@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	tl "github.com/JoelOtter/termloop"
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
 )
 
 // ResizeListener updates terminal width and height every frame
@@ -43,9 +46,49 @@ func (b *GameListener) Tick(e tl.Event) {
 	Game.deltaTime = now - Game.timeOfLastTick
 	Game.timeOfLastTick = now
 
+	Game.HandleMessages()
+
 	board := Game.board
 
-	if e.Type == tl.EventKey {
+	if e.Type == tl.EventKey && Game.typingChat {
+		switch e.Key {
+		case tl.KeyEnter:
+			Game.SendChat()
+		case tl.KeyEsc:
+			Game.typingChat = false
+		case tl.KeyBackspace, tl.KeyBackspace2:
+			Game.DeleteChat()
+		case tl.KeySpace:
+			Game.TypeChat(' ')
+		default:
+			Game.TypeChat(e.Ch)
+		}
+	} else if e.Type == tl.EventKey && Game.takebackRequested {
+		switch e.Ch {
+		case 'y':
+			Game.AnswerTakeback(true)
+		case 'n':
+			Game.AnswerTakeback(false)
+		}
+	} else if e.Type == tl.EventKey && Game.drawOffered {
+		switch e.Ch {
+		case 'y':
+			Game.AnswerDraw(true)
+		case 'n':
+			Game.AnswerDraw(false)
+		}
+	} else if e.Type == tl.EventKey && board.IsChoosingPromotion() {
+		switch e.Key {
+		case tl.KeyArrowRight, tl.KeyArrowDown:
+			board.ChangePromotionChoice(1)
+		case tl.KeyArrowLeft, tl.KeyArrowUp:
+			board.ChangePromotionChoice(-1)
+		case tl.KeyEnter:
+			board.ConfirmPromotion()
+		case tl.KeyEsc:
+			board.CancelPromotion()
+		}
+	} else if e.Type == tl.EventKey {
 		switch e.Key {
 		case tl.KeyArrowRight:
 			board.ChangeSelectedSpot(1, 0)
@@ -58,11 +101,22 @@ func (b *GameListener) Tick(e tl.Event) {
 		case tl.KeyEnter:
 			board.PickSpot()
 		}
+
+		switch e.Ch {
+		case 'd':
+			Game.ClaimDraw()
+		case 't':
+			Game.RequestTakeback()
+		case 'o':
+			Game.OfferDraw()
+		case 'R':
+			Game.Resign()
+		case 'c':
+			Game.StartChat()
+		}
 	}
 
-	// if Game.turn == Game.color {
-	// 	Game.time -= deltaTime
-	// }
+	Game.TickClock(Game.deltaTime)
 }
 
 // SetupGameLevel sets up the game level and returns it
@@ -82,10 +136,38 @@ type MenuListener struct {
 	buttons     []*tl.Rectangle
 	buttonsText []*tl.Text
 	currentBtn  int
+
+	// index of the chosen time control preset, -1 if it isn't one
+	preset int
+
+	// text under the buttons showing the room code and errors
+	status *tl.Text
+
+	// connection to the server once a game has been created or joined
+	connection *ServerConnection
+
+	// room code being typed in to join a game
+	enteringCode bool
+	code         string
+
+	// whether the player has created a room and is waiting in it, esc leaves it
+	hosting bool
 }
 
 // Tick executes events every tick
 func (ml *MenuListener) Tick(e tl.Event) {
+	ml.handleMessages()
+
+	if e.Type == tl.EventKey && ml.enteringCode {
+		ml.editCode(e)
+		return
+	}
+
+	if e.Type == tl.EventKey && ml.hosting && e.Key == tl.KeyEsc {
+		ml.leaveRoom()
+		return
+	}
+
 	if e.Type == tl.EventKey {
 		// remove highlight from current button
 		if ml.currentBtn != 0 {
@@ -107,9 +189,27 @@ func (ml *MenuListener) Tick(e tl.Event) {
 		case tl.KeyEnter:
 			switch ml.currentBtn {
 			case 1:
-				// TODO write create game function
+				ml.createGame()
 			case 2:
-				// TODO write join game function
+				if ml.hosting {
+					break
+				}
+
+				ml.enteringCode = true
+				ml.code = ""
+				ml.status.SetText("Room code: _  (enter to join, esc to cancel)")
+			case 3:
+				ml.changeTimeControl(1)
+			case 4:
+				ml.practice()
+			}
+		case tl.KeyArrowRight:
+			if ml.currentBtn == 3 {
+				ml.changeTimeControl(1)
+			}
+		case tl.KeyArrowLeft:
+			if ml.currentBtn == 3 {
+				ml.changeTimeControl(-1)
 			}
 		}
 
@@ -119,6 +219,196 @@ func (ml *MenuListener) Tick(e tl.Event) {
 	}
 }
 
+// createGame asks the server for a new room and waits for an opponent to join it
+// pressing it again does nothing until the player leaves the room
+func (ml *MenuListener) createGame() {
+	if ml.hosting || !ml.connect() {
+		return
+	}
+
+	if err := ml.connection.CreateRoom(Game.clock.Control, StartFEN); err != nil {
+		ml.disconnect(err)
+		return
+	}
+
+	ml.hosting = true
+	ml.status.SetText("Creating room...")
+}
+
+// leaveRoom leaves the room the player created by hanging up, the server then closes it
+func (ml *MenuListener) leaveRoom() {
+	ml.connection.Close()
+	ml.connection = nil
+	ml.hosting = false
+	ml.status.SetText("Left the room")
+}
+
+// practice starts a game on this computer where the player moves both sides
+func (ml *MenuListener) practice() {
+	if ml.connection != nil {
+		return
+	}
+
+	if err := Game.StartPracticeGame(); err != nil {
+		ml.status.SetText(fmt.Sprintf("Could not start practice game: %v", err))
+		return
+	}
+
+	Screen.SetLevel(SetupGameLevel())
+}
+
+// joinGame asks the server to join the room with the code that was typed in
+func (ml *MenuListener) joinGame() {
+	if !ml.connect() {
+		return
+	}
+
+	if err := ml.connection.JoinRoom(ml.code); err != nil {
+		ml.disconnect(err)
+		return
+	}
+
+	ml.status.SetText("Joining room " + ml.code + "...")
+}
+
+// editCode types e into the room code or submits it
+func (ml *MenuListener) editCode(e tl.Event) {
+	switch e.Key {
+	case tl.KeyEnter:
+		ml.enteringCode = false
+		ml.joinGame()
+		return
+	case tl.KeyEsc:
+		ml.enteringCode = false
+		ml.status.SetText("")
+		return
+	case tl.KeyBackspace, tl.KeyBackspace2:
+		if len(ml.code) > 0 {
+			ml.code = ml.code[:len(ml.code)-1]
+		}
+	default:
+		if e.Ch != 0 && len(ml.code) < 6 {
+			ml.code += strings.ToUpper(string(e.Ch))
+		}
+	}
+
+	ml.status.SetText("Room code: " + ml.code + "_  (enter to join, esc to cancel)")
+}
+
+// connect connects to the server if the menu isn't already connected
+// returns false and shows the error if it can't connect
+func (ml *MenuListener) connect() bool {
+	if ml.connection != nil {
+		return true
+	}
+
+	ml.status.SetText("Connecting to " + ServerAddr + "...")
+
+	connection, err := Dial(ServerAddr, Game.you.name)
+	if err != nil {
+		ml.disconnect(err)
+		return false
+	}
+
+	ml.connection = connection
+	return true
+}
+
+// disconnect closes the connection to the server and shows why
+func (ml *MenuListener) disconnect(err error) {
+	if ml.connection != nil {
+		ml.connection.Close()
+		ml.connection = nil
+	}
+
+	ml.hosting = false
+
+	ml.status.SetText(fmt.Sprintf("Could not reach server: %v", err))
+}
+
+// handleMessages reacts to messages from the server until the game starts
+func (ml *MenuListener) handleMessages() {
+	for ml.connection != nil {
+		msg, ok, closed := ml.connection.Poll()
+		if closed {
+			ml.disconnect(ml.connection.Err())
+			return
+		} else if !ok {
+			return
+		}
+
+		switch msg := msg.(type) {
+		case *protocol.RoomCreated:
+			ml.status.SetText("Room code: " + msg.Code + "  waiting for opponent... (esc to leave)")
+		case *protocol.GameStart:
+			if err := Game.StartNetworkGame(ml.connection, msg); err != nil {
+				ml.disconnect(err)
+				return
+			}
+
+			Screen.SetLevel(SetupGameLevel())
+			return
+		case *protocol.Error:
+			ml.status.SetText(msg.Message)
+			ml.hosting = false
+
+			// the server hangs up on clients with an old protocol version
+			if msg.Code == protocol.ErrUnsupportedVersion {
+				ml.connection.Close()
+				ml.connection = nil
+				return
+			}
+		}
+	}
+}
+
+// changeTimeControl moves step presets through the time controls and starts the game's clock with it
+// the time control can't change once a room has been created with it
+func (ml *MenuListener) changeTimeControl(step int) {
+	if ml.connection != nil {
+		return
+	}
+
+	ml.preset = (ml.preset + step + len(chess.Presets)) % len(chess.Presets)
+
+	control, _ := chess.ParseTimeControl(chess.Presets[ml.preset].Control)
+	Game.StartClock(control)
+
+	ml.setButtonText(3, timeControlText())
+}
+
+// setButtonText changes the text of button btn and centers it
+func (ml *MenuListener) setButtonText(btn int, text string) {
+	button := ml.buttons[btn-1]
+	x, y := button.Position()
+	width, _ := button.Size()
+
+	buttonText := ml.buttonsText[btn-1]
+	buttonText.SetText(text)
+	buttonText.SetPosition(x+width/2-len(text)/2, y+1)
+}
+
+// currentPreset returns the index of the preset the game's time control is or -1 if it isn't one
+func currentPreset() int {
+	control := Game.clock.Control.String()
+	for i, preset := range chess.Presets {
+		if preset.Control == control {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// timeControlText returns the text of the time control button for the game's time control
+func timeControlText() string {
+	if preset := currentPreset(); preset != -1 {
+		return fmt.Sprintf("Time: %s (%s)", chess.Presets[preset].Name, chess.Presets[preset].Control)
+	}
+
+	return fmt.Sprintf("Time: %s", Game.clock.Control)
+}
+
 func addButton(l *tl.BaseLevel, ml *MenuListener, text string, x int, y int, width int) {
 	button := tl.NewRectangle(x, y, width, 3, tl.ColorBlack)
 	buttonText := tl.NewText(x+width/2-len(text)/2, y+1, text, tl.ColorWhite, tl.ColorBlack)
@@ -136,11 +426,16 @@ func SetupMainMenuLevel() *tl.BaseLevel {
 	level.AddEntity(&ResizeListener{tl.NewEntity(0, 0, 0, 0)})
 
 	// add listener
-	ml := &MenuListener{tl.NewEntity(0, 0, 0, 0), make([]*tl.Rectangle, 0), make([]*tl.Text, 0), 0}
+	ml := &MenuListener{
+		Entity:      tl.NewEntity(0, 0, 0, 0),
+		buttons:     make([]*tl.Rectangle, 0),
+		buttonsText: make([]*tl.Text, 0),
+		preset:      currentPreset(),
+	}
 	level.AddEntity(ml)
 
 	// add background
-	level.AddEntity(tl.NewRectangle(1, 1, 57, 23, tl.ColorWhite))
+	level.AddEntity(tl.NewRectangle(1, 1, 57, 27, tl.ColorWhite))
 
 	// add title
 	titleEntity := tl.NewEntityFromCanvas(7, 5, tl.CanvasFromString(BigTitleText))
@@ -152,6 +447,12 @@ func SetupMainMenuLevel() *tl.BaseLevel {
 	// add buttons
 	addButton(level, ml, "Create Game", 7, 13, 44)
 	addButton(level, ml, "Join Game", 7, 17, 44)
+	addButton(level, ml, timeControlText(), 7, 21, 44)
+	addButton(level, ml, "Practice", 7, 25, 44)
+
+	// add status
+	ml.status = tl.NewText(7, 29, "", tl.ColorWhite, tl.ColorBlack)
+	level.AddEntity(ml.status)
 
 	return level
 }
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	tl "github.com/JoelOtter/termloop"
+	"github.com/freddie-nelson/chess/chess"
 )
 
 // Size is the width and height of the board
@@ -16,24 +23,111 @@ var TerminalHeight int = 10
 // Game global game controller
 var Game GameController
 
+// Screen the screen levels are shown on
+var Screen *tl.Screen
+
+// ServerAddr address of the game server networked games are played on
+var ServerAddr string
+
+// StartFEN FEN of the position practice games and created networked games start from, empty for the starting position
+var StartFEN string
+
 func main() {
-	game := tl.NewGame()
-	screen := game.Screen()
+	if len(os.Args) > 1 && os.Args[1] == "perft" {
+		os.Exit(runPerft(os.Args[2:]))
+	}
+
+	fen := flag.String("fen", "", "FEN string of the position practice games and networked games you create start from")
+	fenFile := flag.String("fen-file", "", "path to a file containing the FEN string of the position practice games and networked games you create start from")
+	pgnDir := flag.String("pgn-dir", "games", "directory finished games are saved to as PGN")
+	replayFile := flag.String("pgn", "", "path to a PGN file to step through with the arrow keys")
+	name := flag.String("name", "Freddie", "name shown to your opponent")
+	flag.StringVar(&ServerAddr, "server", "localhost:7777", "address of the game server to create and join games on")
+	timeControl := flag.String("time", "10+0", "time control such as 5+3, 40/90+30,30+30 or 25+0,d5 with a simple delay (b for Bronstein), or a preset: bullet, blitz, rapid, classical or delay")
+	flag.Parse()
 
 	board := Board{}
 	board.Setup()
+
+	control, err := chess.ParseTimeControl(*timeControl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chess: %v\n", err)
+		os.Exit(1)
+	}
+
+	// load starting position before entering the game screen so errors can be printed
+	if err := loadStartingPosition(&board, *fen, *fenFile); err != nil {
+		fmt.Fprintf(os.Stderr, "chess: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *fen != "" || *fenFile != "" {
+		StartFEN = board.ToFEN()
+	}
+
+	// player starts as the side to move
 	Game.board = &board
-	Game.color = White
-	Game.opponentColor = Black
+	Game.color = board.position.Turn
+	Game.opponentColor = board.position.Turn.Opponent()
 
 	// setup users temp
-	Game.you = &User{"Freddie", 600000, false}
-	Game.opponent = &User{"GM Hikaru", 600000, true}
+	Game.you = &User{*name, 0, false}
+	Game.opponent = &User{"GM Hikaru", 0, true}
+	Game.StartClock(control)
 
-	mainMenuLevel := SetupMainMenuLevel()
-	screen.SetLevel(mainMenuLevel)
+	// record moves from the starting position
+	Game.pgnDir = *pgnDir
+	Game.ResetHistory()
 
-	screen.SetFps(24)
+	var games []*chess.PGN
+	if *replayFile != "" {
+		var err error
+		games, err = LoadPGNFile(*replayFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "chess: could not load games: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	game := tl.NewGame()
+	Screen = game.Screen()
+
+	if games != nil {
+		Screen.SetLevel(SetupReplayLevel(games))
+	} else {
+		Screen.SetLevel(SetupMainMenuLevel())
+	}
+
+	Screen.SetFps(24)
 
 	game.Start()
 }
+
+// loadStartingPosition sets up the board from the FEN given on the command line, if any
+func loadStartingPosition(board *Board, fen string, fenFile string) error {
+	if fen != "" && fenFile != "" {
+		return errors.New("only one of --fen and --fen-file can be given")
+	}
+
+	if fenFile != "" {
+		contents, err := os.ReadFile(fenFile)
+		if err != nil {
+			return err
+		}
+
+		fen = strings.TrimSpace(string(contents))
+		if fen == "" {
+			return fmt.Errorf("%s does not contain a FEN string", fenFile)
+		}
+	}
+
+	if fen == "" {
+		return nil
+	}
+
+	if err := board.GenerateFromFENString(fen); err != nil {
+		return fmt.Errorf("could not load position %q: %v", fen, err)
+	}
+
+	return nil
+}
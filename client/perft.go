@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+)
+
+// runPerft runs the perft subcommand with args and returns the exit code
+// prints the number of positions reached after each legal move followed by the total
+func runPerft(args []string) int {
+	flags := flag.NewFlagSet("perft", flag.ContinueOnError)
+	fen := flags.String("fen", chess.StartingFEN, "FEN string of the position to count from")
+	depth := flags.Int("depth", 4, "number of plies to search")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *depth < 1 {
+		fmt.Fprintln(os.Stderr, "chess: perft depth must be at least 1")
+		return 2
+	}
+
+	position, err := chess.ParseFEN(*fen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chess: could not load position %q: %v\n", *fen, err)
+		return 1
+	}
+
+	start := time.Now()
+	counts := chess.Divide(position, *depth)
+
+	moves := make([]chess.Move, 0, len(counts))
+	for m := range counts {
+		moves = append(moves, m)
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].String() < moves[j].String()
+	})
+
+	total := 0
+	for _, m := range moves {
+		fmt.Printf("%s: %d\n", m, counts[m])
+		total += counts[m]
+	}
+
+	fmt.Printf("\nNodes searched: %d (%v)\n", total, time.Since(start).Round(time.Millisecond))
+
+	return 0
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tl "github.com/JoelOtter/termloop"
+	"github.com/freddie-nelson/chess/chess"
+)
+
+// Replay steps through the plies of games loaded from a PGN file
+type Replay struct {
+	games []*chess.PGN
+	game  int
+	ply   int
+
+	// position after ply moves of the current game and the records to take those moves back
+	position *chess.Position
+	undos    []chess.Undo
+}
+
+// LoadPGNFile reads every game in the PGN file at path
+func LoadPGNFile(path string) ([]*chess.PGN, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	games, err := chess.ParsePGN(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(games) == 0 {
+		return nil, fmt.Errorf("%s does not contain any games", path)
+	}
+
+	return games, nil
+}
+
+// SetGame switches to game index and shows it's starting position
+func (r *Replay) SetGame(index int) {
+	if index < 0 || index >= len(r.games) {
+		return
+	}
+
+	r.game = index
+	pgn := r.games[index]
+
+	r.position = pgn.StartPosition()
+	r.undos = nil
+	r.ply = 0
+	Game.board.position = r.position
+
+	Game.you = &User{pgn.Tag("White"), 0, false}
+	Game.opponent = &User{pgn.Tag("Black"), 0, true}
+
+	r.SetPly(0)
+}
+
+// SetPly shows the position after ply moves of the current game
+func (r *Replay) SetPly(ply int) {
+	moves := r.games[r.game].Moves
+	if ply < 0 || ply > len(moves) {
+		return
+	}
+
+	// play or take back moves until the position is reached
+	for r.ply < ply {
+		r.undos = append(r.undos, r.position.MakeMove(moves[r.ply]))
+		r.ply++
+	}
+
+	for r.ply > ply {
+		r.ply--
+		r.position.UnmakeMove(r.undos[r.ply])
+		r.undos = r.undos[:r.ply]
+	}
+
+	board := Game.board
+
+	// highlight the move that reached the position
+	board.ClearHighlighted()
+	if ply > 0 {
+		move := r.games[r.game].Moves[ply-1]
+		board.SquareSpot(move.From).highlighted = true
+		board.SquareSpot(move.To).highlighted = true
+	}
+}
+
+// Status returns a line describing the current game, move and comment
+func (r *Replay) Status() string {
+	pgn := r.games[r.game]
+	status := fmt.Sprintf("Game %v/%v  %s  Ply %v/%v", r.game+1, len(r.games), pgn.Result, r.ply, len(pgn.Moves))
+
+	if r.ply > 0 {
+		// describe the last move from the position before it
+		undo := r.undos[r.ply-1]
+		previous := *r.position
+		previous.UnmakeMove(undo)
+
+		number := fmt.Sprintf("%v.", previous.FullmoveNumber)
+		if previous.Turn == chess.Black {
+			number = fmt.Sprintf("%v...", previous.FullmoveNumber)
+		}
+
+		status += fmt.Sprintf("  %s %s", number, previous.SAN(undo.Move))
+
+		if comment := pgn.Comments[r.ply-1]; comment != "" {
+			status += " {" + comment + "}"
+		}
+	}
+
+	return status
+}
+
+// ReplayListener moves through the replay with the arrow keys
+// left and right step through plies and up and down switch between games
+type ReplayListener struct {
+	*tl.Entity
+	replay *Replay
+}
+
+// Draw draws the board and the replay status below it
+func (r *ReplayListener) Draw(s *tl.Screen) {
+	output := Game.board.ToString()
+	fmt.Printf("\033[4;0H%s", output)
+
+	// status goes below the player's band at the bottom of the board
+	fmt.Printf("\033[%v;0H\033[K%s", 3*Size+7, r.replay.Status())
+}
+
+// Tick reacts to key presses every tick
+func (r *ReplayListener) Tick(e tl.Event) {
+	if e.Type != tl.EventKey {
+		return
+	}
+
+	switch e.Key {
+	case tl.KeyArrowRight:
+		r.replay.SetPly(r.replay.ply + 1)
+	case tl.KeyArrowLeft:
+		r.replay.SetPly(r.replay.ply - 1)
+	case tl.KeyArrowDown:
+		r.replay.SetGame(r.replay.game + 1)
+	case tl.KeyArrowUp:
+		r.replay.SetGame(r.replay.game - 1)
+	case tl.KeyHome:
+		r.replay.SetPly(0)
+	case tl.KeyEnd:
+		r.replay.SetPly(len(r.replay.games[r.replay.game].Moves))
+	}
+}
+
+// SetupReplayLevel sets up a level that replays games and returns it
+func SetupReplayLevel(games []*chess.PGN) *tl.BaseLevel {
+	replay := &Replay{games: games}
+	replay.SetGame(0)
+
+	level := tl.NewBaseLevel(tl.Cell{})
+	level.AddEntity(&ResizeListener{tl.NewEntity(0, 0, 0, 0)})
+	level.AddEntity(&ReplayListener{tl.NewEntity(0, 0, 0, 0), replay})
+
+	return level
+}
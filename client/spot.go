@@ -2,12 +2,9 @@ package main
 
 // Spot identifies a location on the board
 type Spot struct {
-	piece         *Piece
-	containsPiece bool
-	file          int
-	rank          int
-	selected      bool
-	picked        bool
-	highlighted   bool
-	passantTarget int
+	file        int
+	rank        int
+	selected    bool
+	picked      bool
+	highlighted bool
 }
@@ -0,0 +1,7 @@
+module github.com/freddie-nelson/chess/server
+
+go 1.16
+
+require github.com/freddie-nelson/chess/chess v0.0.0
+
+replace github.com/freddie-nelson/chess/chess => ../chess
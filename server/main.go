@@ -1,7 +1,25 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"log"
+	"net"
+	"time"
+)
 
 func main() {
-	fmt.Println("Hello World!")
+	addr := flag.String("addr", ":7777", "address to listen for clients on")
+	lagCap := flag.Duration("lag-cap", 500*time.Millisecond, "most time given back to a move to make up for network lag")
+	grace := flag.Duration("grace", time.Minute, "how long a player who loses their connection has to come back before they lose")
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("chess server: %v", err)
+	}
+
+	log.Printf("listening on %s", listener.Addr())
+
+	server := NewServer(*lagCap, *grace)
+	log.Fatal(server.Serve(listener))
 }
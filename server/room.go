@@ -0,0 +1,639 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
+)
+
+// roomMove is a move in a room's history
+type roomMove struct {
+	undo chess.Undo
+
+	// both clocks and the moves each player had made before the move, restored when it is taken back
+	remainingBefore [2]time.Duration
+	movesBefore     [2]int
+}
+
+// Room is a game between two players
+type Room struct {
+	code    string
+	control chess.TimeControl
+
+	mu sync.Mutex
+
+	// players by color, the creator waits in white's slot until the game starts
+	players [2]*player
+	started bool
+	ended   bool
+
+	position    *chess.Position
+	history     []roomMove
+	repetitions map[uint64]int
+
+	// clocks kept by the server, the player to move's clock has been running since turnStart
+	clock     *chess.Clock
+	turnStart time.Time
+	flagTimer *time.Timer
+
+	// most time taken off a move to make up for it travelling to the server
+	lagCap time.Duration
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+
+	// players waiting on an answer to their takeback request or draw offer, nobody if there is none
+	takebackRequester chess.Color
+	drawOfferer       chess.Color
+
+	// resume tokens of each player, which of them are connected
+	// and the timers that end the game if a disconnected player doesn't come back
+	tokens      [2]string
+	connected   [2]bool
+	graceTimers [2]*time.Timer
+	grace       time.Duration
+
+	// result sent to the players once the game is over
+	result *protocol.GameOver
+
+	// onClose is called once the room's game is over or it is abandoned before starting
+	onClose func()
+	closed  bool
+}
+
+// nobody is the color of a player that doesn't exist
+const nobody chess.Color = -1
+
+// NewRoom returns an empty room playing from position
+// moves are given up to lagCap back to make up for the time they spent travelling to the server
+// and players who lose their connection have grace to come back
+func NewRoom(code string, control chess.TimeControl, position *chess.Position, lagCap time.Duration, grace time.Duration) *Room {
+	return &Room{
+		code:              code,
+		control:           control,
+		position:          position,
+		repetitions:       map[uint64]int{position.Hash(): 1},
+		clock:             chess.NewClock(control),
+		lagCap:            lagCap,
+		now:               time.Now,
+		takebackRequester: nobody,
+		drawOfferer:       nobody,
+		grace:             grace,
+	}
+}
+
+// Ended returns true if the room's game is over
+func (r *Room) Ended() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.ended
+}
+
+// Join adds p to the room as the second player and starts the game
+// each player is given one of tokens to resume the game with
+func (r *Room) Join(p *player, creatorWhite bool, tokens [2]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started || r.ended {
+		return errors.New("room " + r.code + " is full")
+	}
+
+	creator := r.players[chess.White]
+	if creatorWhite {
+		r.players = [2]*player{creator, p}
+	} else {
+		r.players = [2]*player{p, creator}
+	}
+
+	for color, player := range r.players {
+		player.color = chess.Color(color)
+	}
+
+	p.room = r
+	r.started = true
+	r.tokens = tokens
+	r.connected = [2]bool{true, true}
+	r.startTurn()
+
+	for _, player := range r.players {
+		player.send(&protocol.GameStart{
+			Code:        r.code,
+			Color:       player.color.String(),
+			Opponent:    r.players[player.color.Opponent()].name,
+			FEN:         r.position.FEN(),
+			TimeControl: r.control.String(),
+			Token:       r.tokens[player.color],
+		})
+	}
+
+	return nil
+}
+
+// Tokens returns the resume tokens of the room's players, empty until the game starts
+func (r *Room) Tokens() [2]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.tokens
+}
+
+// Leave removes p from the room when they disconnect
+// if the game is in progress they have the grace period to resume it before they lose
+func (r *Room) Leave(p *player) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// p has already been replaced by a newer connection
+	if r.players[p.color] != p {
+		return
+	}
+
+	if !r.started || r.ended {
+		r.ended = true
+		r.close()
+		return
+	}
+
+	color := p.color
+	r.connected[color] = false
+	r.graceTimers[color] = time.AfterFunc(r.grace, func() { r.abandon(color) })
+
+	r.players[color.Opponent()].send(&protocol.OpponentStatus{Connected: false, Grace: milliseconds(r.grace)})
+}
+
+// abandon ends the game with color losing if they haven't come back
+func (r *Room) abandon(color chess.Color) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ended || r.connected[color] {
+		return
+	}
+
+	r.end(chess.Resignation, color)
+}
+
+// Resume seats p in place of the player token was given to and sends them the game so far
+// returns false if the game has ended and p was only sent the result
+func (r *Room) Resume(p *player, token string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	color := nobody
+	for c, t := range r.tokens {
+		if t == token {
+			color = chess.Color(c)
+		}
+	}
+
+	if color == nobody {
+		return false, errors.New("unknown resume token")
+	}
+
+	if r.ended {
+		if r.result == nil {
+			return false, errors.New("game is over")
+		}
+
+		p.send(r.result)
+		return false, nil
+	}
+
+	// the old connection may not have noticed it was dropped yet
+	old := r.players[color]
+	if r.connected[color] {
+		old.conn.Close()
+	}
+
+	if r.graceTimers[color] != nil {
+		r.graceTimers[color].Stop()
+		r.graceTimers[color] = nil
+	}
+
+	p.name = old.name
+	p.color = color
+	p.room = r
+	r.players[color] = p
+	r.connected[color] = true
+
+	moves := make([]string, len(r.history))
+	for i, played := range r.history {
+		moves[i] = played.undo.Move.UCI()
+	}
+
+	opponent := color.Opponent()
+	clocks := r.clockSync()
+
+	p.send(&protocol.GameState{
+		Code:              r.code,
+		Color:             color.String(),
+		Opponent:          r.players[opponent].name,
+		StartFEN:          r.startFEN(),
+		Moves:             moves,
+		FEN:               r.position.FEN(),
+		TimeControl:       r.control.String(),
+		WhiteClock:        clocks.WhiteClock,
+		BlackClock:        clocks.BlackClock,
+		OpponentConnected: r.connected[opponent],
+		Token:             token,
+	})
+
+	// offers that were waiting for an answer when the connection was lost
+	if r.takebackRequester == opponent {
+		p.send(&protocol.TakebackRequest{})
+	}
+
+	if r.drawOfferer == opponent {
+		p.send(&protocol.DrawOffer{})
+	}
+
+	r.players[opponent].send(&protocol.OpponentStatus{Connected: true})
+
+	return true, nil
+}
+
+// startFEN returns the position the game started from, r.mu must be held
+func (r *Room) startFEN() string {
+	start := *r.position
+	for i := len(r.history) - 1; i >= 0; i-- {
+		start.UnmakeMove(r.history[i].undo)
+	}
+
+	return start.FEN()
+}
+
+// Move plays the move p sent if it is legal and their turn
+func (r *Room) Move(p *player, msg *protocol.Move) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInProgress(p); err != nil {
+		return err
+	}
+
+	if r.position.Turn != p.color {
+		return errors.New("not your turn")
+	}
+
+	move, err := r.position.ParseUCI(msg.Move)
+	if err != nil {
+		return err
+	}
+
+	// a move that arrives after the player's time is up loses on time
+	elapsed := r.elapsed(true)
+	if elapsed >= r.clock.TimeLeft(p.color) {
+		r.flag()
+		return nil
+	}
+
+	r.clock.Tick(p.color, elapsed)
+	r.history = append(r.history, roomMove{
+		remainingBefore: [2]time.Duration{r.clock.Remaining(chess.White), r.clock.Remaining(chess.Black)},
+		movesBefore:     [2]int{r.clock.Moves(chess.White), r.clock.Moves(chess.Black)},
+	})
+
+	r.history[len(r.history)-1].undo = r.position.MakeMove(move)
+	r.repetitions[r.position.Hash()]++
+
+	r.clock.Press(p.color)
+	r.startTurn()
+
+	// a move cancels any takeback request or draw offer
+	r.takebackRequester = nobody
+	r.drawOfferer = nobody
+
+	r.broadcast(&protocol.MoveMade{
+		Move:       move.UCI(),
+		WhiteClock: milliseconds(r.clock.Remaining(chess.White)),
+		BlackClock: milliseconds(r.clock.Remaining(chess.Black)),
+	})
+
+	if status := r.position.Status(r.repetitions[r.position.Hash()]); status != chess.Ongoing {
+		r.end(status, r.position.Turn)
+	}
+
+	return nil
+}
+
+// Resign ends the game with p losing
+func (r *Room) Resign(p *player) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInProgress(p); err != nil {
+		return err
+	}
+
+	r.end(chess.Resignation, p.color)
+
+	return nil
+}
+
+// RequestTakeback asks p's opponent to let p take back their last move
+func (r *Room) RequestTakeback(p *player) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInProgress(p); err != nil {
+		return err
+	}
+
+	if r.takebackRequester != nobody {
+		return errors.New("a takeback has already been requested")
+	}
+
+	if r.position.TakebackPlies(p.color, len(r.history)) == 0 {
+		return errors.New("no move to take back")
+	}
+
+	r.takebackRequester = p.color
+
+	r.players[p.color.Opponent()].send(&protocol.TakebackRequest{})
+
+	return nil
+}
+
+// AnswerTakeback answers the takeback request made by p's opponent
+// accepted takebacks take back moves until it is the requester's turn again and restore the clocks
+func (r *Room) AnswerTakeback(p *player, accept bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInProgress(p); err != nil {
+		return err
+	}
+
+	requester := r.takebackRequester
+	if requester != p.color.Opponent() {
+		return errors.New("no takeback to answer")
+	}
+
+	r.takebackRequester = nobody
+
+	if accept {
+		plies := r.position.TakebackPlies(requester, len(r.history))
+		first := r.history[len(r.history)-plies]
+
+		for i := 0; i < plies; i++ {
+			last := len(r.history) - 1
+			r.repetitions[r.position.Hash()]--
+			r.position.UnmakeMove(r.history[last].undo)
+			r.history = r.history[:last]
+		}
+
+		for _, color := range []chess.Color{chess.White, chess.Black} {
+			r.clock.SetRemaining(color, first.remainingBefore[color], first.movesBefore[color])
+		}
+
+		r.drawOfferer = nobody
+		r.startTurn()
+	}
+
+	// both players take back the moves once the server has, a declined request only concerns the requester
+	if accept {
+		r.broadcast(&protocol.TakebackAnswer{Accept: true})
+		r.broadcast(r.clockSync())
+	} else {
+		r.players[requester].send(&protocol.TakebackAnswer{Accept: false})
+	}
+
+	return nil
+}
+
+// OfferDraw claims a draw for p if they are to move and can claim one,
+// agrees to their opponent's offer if they made one or offers their opponent a draw
+func (r *Room) OfferDraw(p *player) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInProgress(p); err != nil {
+		return err
+	}
+
+	if r.position.Turn == p.color {
+		if claim := r.position.ClaimableDraw(r.repetitions[r.position.Hash()]); claim != chess.Ongoing {
+			r.end(claim, p.color)
+			return nil
+		}
+	}
+
+	if r.drawOfferer == p.color {
+		return errors.New("a draw has already been offered")
+	} else if r.drawOfferer != nobody {
+		r.end(chess.DrawAgreed, p.color)
+		return nil
+	}
+
+	r.drawOfferer = p.color
+	r.players[p.color.Opponent()].send(&protocol.DrawOffer{})
+
+	return nil
+}
+
+// AnswerDraw answers the draw offer made by p's opponent
+func (r *Room) AnswerDraw(p *player, accept bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkInProgress(p); err != nil {
+		return err
+	}
+
+	offerer := r.drawOfferer
+	if offerer != p.color.Opponent() {
+		return errors.New("no draw offer to answer")
+	}
+
+	r.drawOfferer = nobody
+
+	if accept {
+		r.end(chess.DrawAgreed, p.color)
+	} else {
+		r.players[offerer].send(&protocol.DrawAnswer{Accept: false})
+	}
+
+	return nil
+}
+
+// Chat passes a message from p on to their opponent
+func (r *Room) Chat(p *player, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		return errors.New("game has not started")
+	}
+
+	if r.players[p.color] != p {
+		return errors.New("game was resumed from another connection")
+	}
+
+	r.players[p.color.Opponent()].send(&protocol.Chat{From: p.name, Text: text})
+
+	return nil
+}
+
+// checkInProgress returns an error if the game hasn't started or has ended
+// or p has been replaced by a newer connection, r.mu must be held
+func (r *Room) checkInProgress(p *player) error {
+	if !r.started {
+		return errors.New("game has not started")
+	}
+
+	if r.players[p.color] != p {
+		return errors.New("game was resumed from another connection")
+	}
+
+	if r.ended {
+		return errors.New("game is over")
+	}
+
+	return nil
+}
+
+// elapsed returns how long the player to move has been thinking, r.mu must be held
+// compensate takes off the time their move spent travelling to the server, up to the lag cap
+func (r *Room) elapsed(compensate bool) time.Duration {
+	elapsed := r.now().Sub(r.turnStart)
+	if compensate {
+		elapsed -= r.compensation(r.position.Turn)
+	}
+
+	if elapsed < 0 {
+		return 0
+	}
+
+	return elapsed
+}
+
+// compensation returns the time given back to color's moves for lag, r.mu must be held
+func (r *Room) compensation(color chess.Color) time.Duration {
+	lag := r.players[color].lag()
+	if lag > r.lagCap {
+		return r.lagCap
+	}
+
+	return lag
+}
+
+// startTurn starts the clock of the player to move and sets a timer to flag them, r.mu must be held
+func (r *Room) startTurn() {
+	r.turnStart = r.now()
+
+	if r.flagTimer != nil {
+		r.flagTimer.Stop()
+	}
+
+	turn := r.position.Turn
+	r.flagTimer = time.AfterFunc(r.clock.TimeLeft(turn)+r.compensation(turn), r.checkFlag)
+}
+
+// checkFlag flags the player to move if their time is up or sets the timer again if it isn't yet
+func (r *Room) checkFlag() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started || r.ended {
+		return
+	}
+
+	// the player's lag may have changed since the timer was set
+	left := r.clock.TimeLeft(r.position.Turn) - r.elapsed(true)
+	if left > 0 {
+		r.flagTimer = time.AfterFunc(left, r.checkFlag)
+		return
+	}
+
+	r.flag()
+}
+
+// flag ends the game with the player to move out of time, r.mu must be held
+func (r *Room) flag() {
+	turn := r.position.Turn
+	r.clock.SetRemaining(turn, 0, r.clock.Moves(turn))
+
+	r.broadcast(r.clockSync())
+	r.end(r.position.TimeoutStatus(turn), turn)
+}
+
+// clockSync returns the time each player has left, r.mu must be held
+func (r *Room) clockSync() *protocol.ClockSync {
+	remaining := [2]time.Duration{r.clock.Remaining(chess.White), r.clock.Remaining(chess.Black)}
+
+	// the player to move's clock has been running since their turn started
+	if r.started && !r.ended {
+		turn := r.position.Turn
+		remaining[turn] = r.clock.TimeLeft(turn) - r.elapsed(false)
+		if remaining[turn] > r.clock.Remaining(turn) {
+			remaining[turn] = r.clock.Remaining(turn)
+		} else if remaining[turn] < 0 {
+			remaining[turn] = 0
+		}
+	}
+
+	return &protocol.ClockSync{
+		WhiteClock: milliseconds(remaining[chess.White]),
+		BlackClock: milliseconds(remaining[chess.Black]),
+	}
+}
+
+// milliseconds returns d in whole milliseconds as sent in messages
+func milliseconds(d time.Duration) int {
+	return int(d.Milliseconds())
+}
+
+// end ends the game because of status and tells the players the result, r.mu must be held
+// loser is the player who was checkmated, resigned or ran out of time and is ignored for draws
+func (r *Room) end(status chess.Status, loser chess.Color) {
+	r.ended = true
+	if r.flagTimer != nil {
+		r.flagTimer.Stop()
+	}
+
+	for _, timer := range r.graceTimers {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	defer r.close()
+
+	msg := &protocol.GameOver{
+		Result: status.Result(loser),
+		Reason: status.String(),
+	}
+
+	if !status.IsDraw() {
+		msg.Loser = loser.String()
+	}
+
+	r.result = msg
+	r.broadcast(msg)
+}
+
+// close lets the server know the room can be removed, r.mu must be held
+func (r *Room) close() {
+	if r.closed || r.onClose == nil {
+		return
+	}
+
+	r.closed = true
+
+	// the server's lock is taken after the room's so the callback can't wait on it
+	go r.onClose()
+}
+
+// broadcast sends msg to both players, r.mu must be held
+func (r *Room) broadcast(msg protocol.Message) {
+	for _, player := range r.players {
+		if player != nil {
+			player.send(msg)
+		}
+	}
+}
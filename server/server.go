@@ -0,0 +1,493 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
+)
+
+// roomCodeChars are the characters room codes are made of, without ones that are easily mixed up
+const roomCodeChars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// roomCodeLength is the number of characters in a room code
+const roomCodeLength = 6
+
+// outboxSize is the number of messages that can wait to be sent to a player before they are disconnected
+const outboxSize = 64
+
+// flushTimeout is how long to wait for a disconnecting player's queued messages to be sent
+const flushTimeout = time.Second
+
+// pingInterval is how often players are pinged to measure their round trip time
+const pingInterval = 2 * time.Second
+
+// resultsKept is how long a finished game's result can still be fetched with a resume token
+const resultsKept = 10 * time.Minute
+
+// Server hosts games between clients
+type Server struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+	rand  *rand.Rand
+
+	// rooms by the resume tokens of their players
+	tokens map[string]*Room
+
+	// most time taken off a move to make up for it travelling to the server
+	lagCap time.Duration
+
+	// how long a player who loses their connection has to come back before they lose
+	grace time.Duration
+
+	// how often players are pinged, 0 to never ping them
+	pingInterval time.Duration
+
+	// how long resume tokens of finished games are kept so players who come back are told the result
+	resultsKept time.Duration
+}
+
+// clientError is an error sent back to the client with a protocol error code
+type clientError struct {
+	code    string
+	message string
+}
+
+func (e *clientError) Error() string {
+	return e.message
+}
+
+// player is a client connected to the server
+type player struct {
+	conn *protocol.Conn
+	name string
+
+	// messages waiting to be sent, closed when the player disconnects
+	outbox chan protocol.Message
+	closed bool
+	mu     sync.Mutex
+
+	// closed once every queued message has been written
+	flushed chan struct{}
+
+	// set once the player has said hello with a supported protocol version
+	greeted bool
+
+	// smoothed round trip time and the ping waiting for a pong, guarded by mu
+	rtt      time.Duration
+	pingID   int
+	pingSent time.Time
+
+	// room the player is in and their color in it, nil until they create or join one
+	room  *Room
+	color chess.Color
+}
+
+// NewServer returns a server with no rooms
+// moves are given up to lagCap back to make up for the time they spent travelling to the server
+// and players who lose their connection have grace to come back
+func NewServer(lagCap time.Duration, grace time.Duration) *Server {
+	return &Server{
+		rooms:        map[string]*Room{},
+		tokens:       map[string]*Room{},
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		lagCap:       lagCap,
+		grace:        grace,
+		pingInterval: pingInterval,
+		resultsKept:  resultsKept,
+	}
+}
+
+// Serve accepts clients from l until it fails
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// handle reads messages from a client until it disconnects
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	p := &player{
+		conn:    protocol.NewConn(conn),
+		outbox:  make(chan protocol.Message, outboxSize),
+		flushed: make(chan struct{}),
+	}
+
+	go p.write()
+	defer p.close()
+	defer s.leave(p)
+
+	for {
+		msg, err := p.conn.Receive()
+		if err != nil {
+			var decodeErr *protocol.DecodeError
+			if !errors.As(err, &decodeErr) {
+				return
+			}
+
+			p.send(&protocol.Error{Code: protocol.ErrBadMessage, Message: err.Error()})
+			continue
+		}
+
+		if err := s.dispatch(p, msg); err != nil {
+			code := protocol.ErrRejected
+
+			var clientErr *clientError
+			if errors.As(err, &clientErr) {
+				code = clientErr.code
+			}
+
+			p.send(&protocol.Error{Code: code, Message: err.Error()})
+
+			// clients that don't speak our version can't do anything else
+			if code == protocol.ErrUnsupportedVersion {
+				return
+			}
+		}
+	}
+}
+
+// send queues msg to be sent to p without waiting for it to be written
+// players too slow to keep up with their messages are disconnected
+func (p *player) send(msg protocol.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.outbox <- msg:
+	default:
+		p.closed = true
+		close(p.outbox)
+		p.conn.Close()
+	}
+}
+
+// write sends the messages queued for p until it's outbox is closed
+func (p *player) write() {
+	defer close(p.flushed)
+
+	for msg := range p.outbox {
+		if err := p.conn.Send(msg); err != nil {
+			p.conn.Close()
+		}
+	}
+}
+
+// close stops sending messages to p and waits for the queued ones to be sent
+func (p *player) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.outbox)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.flushed:
+	case <-time.After(flushTimeout):
+	}
+}
+
+// ping pings p every interval until they disconnect
+func (p *player) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for id := 1; ; id++ {
+		p.mu.Lock()
+		p.pingID = id
+		p.pingSent = time.Now()
+		p.mu.Unlock()
+
+		p.send(&protocol.Ping{ID: id})
+
+		select {
+		case <-ticker.C:
+		case <-p.flushed:
+			return
+		}
+	}
+}
+
+// pong measures the round trip time of the ping with id
+func (p *player) pong(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id != p.pingID || p.pingSent.IsZero() {
+		return
+	}
+
+	sample := time.Since(p.pingSent)
+	p.pingSent = time.Time{}
+
+	if p.rtt == 0 {
+		p.rtt = sample
+	} else {
+		p.rtt = (7*p.rtt + sample) / 8
+	}
+}
+
+// lag returns how long a message from p takes to reach the server, estimated as half the round trip time
+func (p *player) lag() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.rtt / 2
+}
+
+// dispatch handles a message from p
+func (s *Server) dispatch(p *player, msg protocol.Message) error {
+	if hello, ok := msg.(*protocol.Hello); ok {
+		return s.hello(p, hello)
+	}
+
+	if !p.greeted {
+		return &clientError{protocol.ErrHelloRequired, "say hello before sending " + msg.Type()}
+	}
+
+	switch msg := msg.(type) {
+	case *protocol.Pong:
+		p.pong(msg.ID)
+		return nil
+	case *protocol.CreateRoom:
+		return s.createRoom(p, msg)
+	case *protocol.JoinRoom:
+		return s.joinRoom(p, msg)
+	case *protocol.Resume:
+		return s.resume(p, msg)
+	}
+
+	if p.room == nil {
+		return errors.New("not in a room")
+	}
+
+	switch msg := msg.(type) {
+	case *protocol.Move:
+		return p.room.Move(p, msg)
+	case *protocol.Resign:
+		return p.room.Resign(p)
+	case *protocol.TakebackRequest:
+		return p.room.RequestTakeback(p)
+	case *protocol.TakebackAnswer:
+		return p.room.AnswerTakeback(p, msg.Accept)
+	case *protocol.DrawOffer:
+		return p.room.OfferDraw(p)
+	case *protocol.DrawAnswer:
+		return p.room.AnswerDraw(p, msg.Accept)
+	case *protocol.Chat:
+		return p.room.Chat(p, msg.Text)
+	}
+
+	return errors.New("unexpected " + msg.Type() + " message")
+}
+
+// hello checks the client speaks a supported protocol version and welcomes them
+func (s *Server) hello(p *player, msg *protocol.Hello) error {
+	if p.greeted {
+		return errors.New("already said hello")
+	}
+
+	if msg.Version < protocol.MinVersion || msg.Version > protocol.Version {
+		return &clientError{
+			protocol.ErrUnsupportedVersion,
+			fmt.Sprintf("protocol version %v is not supported, this server needs version %v to %v, please update your client", msg.Version, protocol.MinVersion, protocol.Version),
+		}
+	}
+
+	p.name = msg.Name
+	p.greeted = true
+	p.send(&protocol.Welcome{Version: protocol.Version})
+
+	if s.pingInterval > 0 {
+		go p.ping(s.pingInterval)
+	}
+
+	return nil
+}
+
+// createRoom makes a new room with p waiting in it
+func (s *Server) createRoom(p *player, msg *protocol.CreateRoom) error {
+	if err := s.leaveEnded(p); err != nil {
+		return err
+	}
+
+	// the time control and position were checked when the message was decoded
+	control, _ := chess.ParseTimeControl(msg.TimeControl)
+	position := chess.StartingPosition()
+	if msg.FEN != "" {
+		position, _ = chess.ParseFEN(msg.FEN)
+	}
+
+	s.mu.Lock()
+	code := s.newRoomCode()
+	room := NewRoom(code, control, position, s.lagCap, s.grace)
+	room.players[chess.White] = p
+	room.onClose = func() { s.removeRoom(room) }
+	s.rooms[code] = room
+	s.mu.Unlock()
+
+	p.room = room
+
+	log.Printf("room %s created by %q", code, p.name)
+
+	p.send(&protocol.RoomCreated{Code: code})
+
+	return nil
+}
+
+// joinRoom adds p to the room with the code in msg and starts it's game
+func (s *Server) joinRoom(p *player, msg *protocol.JoinRoom) error {
+	if err := s.leaveEnded(p); err != nil {
+		return err
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(msg.Code))
+
+	s.mu.Lock()
+	room, ok := s.rooms[code]
+	creatorWhite := s.rand.Intn(2) == 0
+	s.mu.Unlock()
+
+	if !ok {
+		return errors.New("no room with code " + code)
+	}
+
+	tokens := [2]string{newToken(), newToken()}
+	if err := room.Join(p, creatorWhite, tokens); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for _, token := range tokens {
+		s.tokens[token] = room
+	}
+	s.mu.Unlock()
+
+	log.Printf("room %s joined by %q", code, p.name)
+
+	return nil
+}
+
+// resume puts p back in the game their token was given for
+func (s *Server) resume(p *player, msg *protocol.Resume) error {
+	if err := s.leaveEnded(p); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	room, ok := s.tokens[msg.Token]
+	s.mu.Unlock()
+
+	if !ok {
+		return errors.New("unknown resume token")
+	}
+
+	resumed, err := room.Resume(p, msg.Token)
+	if err != nil {
+		return err
+	}
+
+	if !resumed {
+		return nil
+	}
+
+	log.Printf("room %s resumed by %q", room.code, p.name)
+
+	return nil
+}
+
+// leave removes p from their room when they disconnect
+func (s *Server) leave(p *player) {
+	if p.room != nil {
+		p.room.Leave(p)
+	}
+}
+
+// removeRoom forgets a room once it's game is over or it was abandoned
+// it's resume tokens are kept for a while so players who come back can be told the result
+func (s *Server) removeRoom(room *Room) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rooms[room.code] == room {
+		delete(s.rooms, room.code)
+	}
+
+	time.AfterFunc(s.resultsKept, func() { s.forgetTokens(room) })
+}
+
+// forgetTokens removes the resume tokens of room
+func (s *Server) forgetTokens(room *Room) {
+	tokens := room.Tokens()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range tokens {
+		if s.tokens[token] == room {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+// newToken returns a random token that can't be guessed
+func newToken() string {
+	token := make([]byte, 16)
+	if _, err := crand.Read(token); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(token)
+}
+
+// leaveEnded takes p out of their room if it's game is over so they can play another
+// returns an error if they are still playing
+func (s *Server) leaveEnded(p *player) error {
+	if p.room == nil {
+		return nil
+	}
+
+	if !p.room.Ended() {
+		return errors.New("already in a room")
+	}
+
+	s.leave(p)
+	p.room = nil
+
+	return nil
+}
+
+// newRoomCode returns a room code that isn't in use, s.mu must be held
+func (s *Server) newRoomCode() string {
+	for {
+		code := make([]byte, roomCodeLength)
+		for i := range code {
+			code[i] = roomCodeChars[s.rand.Intn(len(roomCodeChars))]
+		}
+
+		if _, ok := s.rooms[string(code)]; !ok {
+			return string(code)
+		}
+	}
+}
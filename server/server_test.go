@@ -0,0 +1,592 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/freddie-nelson/chess/chess"
+	"github.com/freddie-nelson/chess/chess/protocol"
+)
+
+// newTestServer returns a server that doesn't ping it's clients so tests only see the messages they expect
+func newTestServer() *Server {
+	s := NewServer(500*time.Millisecond, 50*time.Millisecond)
+	s.pingInterval = 0
+
+	return s
+}
+
+// connect starts handling a new client on s and returns it's end of the connection
+func connect(t *testing.T, s *Server) *protocol.Conn {
+	t.Helper()
+
+	client, server := net.Pipe()
+	go s.handle(server)
+	t.Cleanup(func() { client.Close() })
+
+	c := protocol.NewConn(client)
+	send(t, c, &protocol.Hello{Version: protocol.Version, Name: "player"})
+	if _, ok := receive(t, c).(*protocol.Welcome); !ok {
+		t.Fatal("client was not welcomed")
+	}
+
+	return c
+}
+
+// send sends msg from c, failing the test if it can't be sent
+func send(t *testing.T, c *protocol.Conn, msg protocol.Message) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- c.Send(msg) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sending %s: %v", msg.Type(), err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("sending %s timed out", msg.Type())
+	}
+}
+
+// receive returns the next message sent to c
+func receive(t *testing.T, c *protocol.Conn) protocol.Message {
+	t.Helper()
+
+	type result struct {
+		msg protocol.Message
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		msg, err := c.Receive()
+		done <- result{msg, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("receiving: %v", r.err)
+		}
+
+		return r.msg
+	case <-time.After(time.Second):
+		t.Fatal("receiving timed out")
+	}
+
+	return nil
+}
+
+// startGame creates a room with one client, joins it with another and returns them as white and black
+func startGame(t *testing.T, s *Server) (*protocol.Conn, *protocol.Conn) {
+	t.Helper()
+
+	white, black, _ := startGameWithToken(t, s)
+
+	return white, black
+}
+
+// startGameWithToken starts a game like startGame and also returns white's resume token
+func startGameWithToken(t *testing.T, s *Server) (*protocol.Conn, *protocol.Conn, string) {
+	t.Helper()
+
+	creator := connect(t, s)
+	send(t, creator, &protocol.CreateRoom{TimeControl: "5+3"})
+
+	created, ok := receive(t, creator).(*protocol.RoomCreated)
+	if !ok {
+		t.Fatal("creator was not sent roomCreated")
+	}
+
+	joiner := connect(t, s)
+	send(t, joiner, &protocol.JoinRoom{Code: created.Code})
+
+	creatorStart, ok := receive(t, creator).(*protocol.GameStart)
+	if !ok {
+		t.Fatal("creator was not sent gameStart")
+	}
+
+	joinerStart, ok := receive(t, joiner).(*protocol.GameStart)
+	if !ok {
+		t.Fatal("joiner was not sent gameStart")
+	}
+
+	if creatorStart.Color == joinerStart.Color {
+		t.Fatalf("both players are %s", creatorStart.Color)
+	}
+
+	if creatorStart.Token == "" || creatorStart.Token == joinerStart.Token {
+		t.Fatal("players were not given their own resume tokens")
+	}
+
+	if creatorStart.Color == "white" {
+		return creator, joiner, creatorStart.Token
+	}
+
+	return joiner, creator, joinerStart.Token
+}
+
+func TestGameStartsFromTheCreatorsPosition(t *testing.T) {
+	s := newTestServer()
+	fen := "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"
+
+	creator := connect(t, s)
+	send(t, creator, &protocol.CreateRoom{TimeControl: "5+3", FEN: fen})
+
+	created, ok := receive(t, creator).(*protocol.RoomCreated)
+	if !ok {
+		t.Fatal("creator was not sent roomCreated")
+	}
+
+	joiner := connect(t, s)
+	send(t, joiner, &protocol.JoinRoom{Code: created.Code})
+
+	for _, c := range []*protocol.Conn{creator, joiner} {
+		start, ok := receive(t, c).(*protocol.GameStart)
+		if !ok || start.FEN != fen {
+			t.Fatalf("gameStart = %+v, want fen %q", start, fen)
+		}
+	}
+}
+
+func TestGameIsPlayedToCheckmate(t *testing.T) {
+	white, black := startGame(t, newTestServer())
+
+	for i, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		mover := white
+		if i%2 == 1 {
+			mover = black
+		}
+
+		send(t, mover, &protocol.Move{Move: move})
+
+		for _, c := range []*protocol.Conn{white, black} {
+			made, ok := receive(t, c).(*protocol.MoveMade)
+			if !ok || made.Move != move {
+				t.Fatalf("move %s was not relayed", move)
+			}
+		}
+	}
+
+	for _, c := range []*protocol.Conn{white, black} {
+		over, ok := receive(t, c).(*protocol.GameOver)
+		if !ok || over.Result != "0-1" || over.Reason != "checkmate" {
+			t.Fatalf("got %+v, want black to win by checkmate", over)
+		}
+	}
+}
+
+func TestInvalidMovesAreRejected(t *testing.T) {
+	white, black := startGame(t, newTestServer())
+
+	send(t, black, &protocol.Move{Move: "e7e5"})
+	if _, ok := receive(t, black).(*protocol.Error); !ok {
+		t.Error("move out of turn was not rejected")
+	}
+
+	send(t, white, &protocol.Move{Move: "e2e5"})
+	if _, ok := receive(t, white).(*protocol.Error); !ok {
+		t.Error("illegal move was not rejected")
+	}
+}
+
+func TestJoinUnknownRoom(t *testing.T) {
+	c := connect(t, newTestServer())
+	send(t, c, &protocol.JoinRoom{Code: "NOROOM"})
+
+	if _, ok := receive(t, c).(*protocol.Error); !ok {
+		t.Error("joining an unknown room was not rejected")
+	}
+}
+
+func TestLeavingForfeitsTheGameAfterTheGracePeriod(t *testing.T) {
+	white, black := startGame(t, newTestServer())
+
+	send(t, white, &protocol.Move{Move: "e2e4"})
+	receive(t, white)
+	receive(t, black)
+
+	white.Close()
+
+	if status, ok := receive(t, black).(*protocol.OpponentStatus); !ok || status.Connected {
+		t.Fatalf("got %+v, want white to be disconnected", status)
+	}
+
+	over, ok := receive(t, black).(*protocol.GameOver)
+	if !ok || over.Result != "0-1" {
+		t.Fatalf("got %+v, want black to win", over)
+	}
+}
+
+func TestResumeAfterLosingConnection(t *testing.T) {
+	s := newTestServer()
+	s.grace = time.Minute
+	white, black, token := startGameWithToken(t, s)
+
+	send(t, white, &protocol.Move{Move: "e2e4"})
+	receive(t, white)
+	receive(t, black)
+
+	send(t, black, &protocol.DrawOffer{})
+	receive(t, white)
+
+	white.Close()
+	receive(t, black)
+
+	resumed := connect(t, s)
+	send(t, resumed, &protocol.Resume{Token: token})
+
+	state, ok := receive(t, resumed).(*protocol.GameState)
+	if !ok {
+		t.Fatal("resumed client was not sent the game state")
+	}
+
+	if state.Color != "white" || len(state.Moves) != 1 || state.Moves[0] != "e2e4" || state.StartFEN != chess.StartingFEN || !state.OpponentConnected {
+		t.Fatalf("got %+v, want white's game after e2e4", state)
+	}
+
+	if state.BlackClock <= 0 || state.BlackClock > 300000 || state.WhiteClock <= 300000 {
+		t.Fatalf("got clocks %v and %v, want white's increment and black's clock running", state.WhiteClock, state.BlackClock)
+	}
+
+	if _, ok := receive(t, resumed).(*protocol.DrawOffer); !ok {
+		t.Fatal("draw offer was not sent again")
+	}
+
+	if status, ok := receive(t, black).(*protocol.OpponentStatus); !ok || !status.Connected {
+		t.Fatalf("got %+v, want white to be back", status)
+	}
+
+	// the game carries on
+	send(t, black, &protocol.Move{Move: "e7e5"})
+	if made, ok := receive(t, resumed).(*protocol.MoveMade); !ok || made.Move != "e7e5" {
+		t.Fatal("move was not relayed to the resumed client")
+	}
+}
+
+func TestResumeAfterTheGameEnded(t *testing.T) {
+	s := newTestServer()
+	white, black, token := startGameWithToken(t, s)
+
+	white.Close()
+	receive(t, black)
+	receive(t, black)
+
+	resumed := connect(t, s)
+	send(t, resumed, &protocol.Resume{Token: token})
+
+	over, ok := receive(t, resumed).(*protocol.GameOver)
+	if !ok || over.Result != "0-1" || over.Reason != "resignation" || over.Loser != "white" {
+		t.Fatalf("got %+v, want white to be told they lost by resignation", over)
+	}
+}
+
+func TestResumeWithUnknownToken(t *testing.T) {
+	c := connect(t, newTestServer())
+	send(t, c, &protocol.Resume{Token: "nope"})
+
+	if _, ok := receive(t, c).(*protocol.Error); !ok {
+		t.Error("resuming with an unknown token was not rejected")
+	}
+}
+
+func TestOldClientsAreRejected(t *testing.T) {
+	client, server := net.Pipe()
+	go newTestServer().handle(server)
+	defer client.Close()
+
+	c := protocol.NewConn(client)
+	send(t, c, &protocol.Hello{Version: protocol.MinVersion - 1, Name: "old"})
+
+	if msg, ok := receive(t, c).(*protocol.Error); !ok || msg.Code != protocol.ErrUnsupportedVersion {
+		t.Fatalf("got %+v, want an unsupported version error", msg)
+	}
+
+	if _, err := c.Receive(); err == nil {
+		t.Error("connection should be closed")
+	}
+}
+
+func TestHelloIsRequired(t *testing.T) {
+	client, server := net.Pipe()
+	go newTestServer().handle(server)
+	defer client.Close()
+
+	c := protocol.NewConn(client)
+	send(t, c, &protocol.CreateRoom{TimeControl: "5+3"})
+
+	if msg, ok := receive(t, c).(*protocol.Error); !ok || msg.Code != protocol.ErrHelloRequired {
+		t.Fatalf("got %+v, want a hello required error", msg)
+	}
+}
+
+func TestBadMessagesGetErrorReplies(t *testing.T) {
+	c := connect(t, newTestServer())
+
+	// the connection is still used after a bad message
+	for i := 0; i < 2; i++ {
+		go c.Send(&protocol.Move{})
+
+		if msg, ok := receive(t, c).(*protocol.Error); !ok || msg.Code != protocol.ErrBadMessage {
+			t.Fatalf("got %+v, want a bad message error", msg)
+		}
+	}
+}
+
+func TestDrawOffers(t *testing.T) {
+	white, black := startGame(t, newTestServer())
+
+	send(t, white, &protocol.DrawOffer{})
+	if _, ok := receive(t, black).(*protocol.DrawOffer); !ok {
+		t.Fatal("draw offer was not passed on")
+	}
+
+	send(t, black, &protocol.DrawAnswer{Accept: false})
+	if answer, ok := receive(t, white).(*protocol.DrawAnswer); !ok || answer.Accept {
+		t.Fatal("declined draw offer was not passed back")
+	}
+
+	send(t, black, &protocol.DrawOffer{})
+	receive(t, white)
+	send(t, white, &protocol.DrawAnswer{Accept: true})
+
+	for _, c := range []*protocol.Conn{white, black} {
+		over, ok := receive(t, c).(*protocol.GameOver)
+		if !ok || over.Result != "1/2-1/2" || over.Reason != "draw agreed" || over.Loser != "" {
+			t.Fatalf("got %+v, want a draw by agreement", over)
+		}
+	}
+}
+
+// playMoves has white and black take turns playing moves and checks each is relayed to both
+func playMoves(t *testing.T, white *protocol.Conn, black *protocol.Conn, moves ...string) {
+	t.Helper()
+
+	for i, move := range moves {
+		mover := white
+		if i%2 == 1 {
+			mover = black
+		}
+
+		send(t, mover, &protocol.Move{Move: move})
+
+		for _, c := range []*protocol.Conn{white, black} {
+			if made, ok := receive(t, c).(*protocol.MoveMade); !ok || made.Move != move {
+				t.Fatalf("move %s was not relayed", move)
+			}
+		}
+	}
+}
+
+// requestTakeback has requester ask for a takeback and checks it is passed on to answerer
+func requestTakeback(t *testing.T, requester *protocol.Conn, answerer *protocol.Conn) {
+	t.Helper()
+
+	send(t, requester, &protocol.TakebackRequest{})
+	if _, ok := receive(t, answerer).(*protocol.TakebackRequest); !ok {
+		t.Fatal("takeback request was not passed on")
+	}
+}
+
+// acceptTakeback has answerer accept a takeback and checks both players are told and sent the restored clocks
+func acceptTakeback(t *testing.T, white *protocol.Conn, black *protocol.Conn, answerer *protocol.Conn) {
+	t.Helper()
+
+	send(t, answerer, &protocol.TakebackAnswer{Accept: true})
+
+	for _, c := range []*protocol.Conn{white, black} {
+		if answer, ok := receive(t, c).(*protocol.TakebackAnswer); !ok || !answer.Accept {
+			t.Fatalf("got %+v, want an accepted takeback", answer)
+		}
+
+		if _, ok := receive(t, c).(*protocol.ClockSync); !ok {
+			t.Fatal("clocks were not synced after the takeback")
+		}
+	}
+}
+
+func TestTakebacks(t *testing.T) {
+	s := newTestServer()
+	white, black := startGame(t, s)
+	room := onlyRoom(t, s)
+
+	playMoves(t, white, black, "e2e4", "e7e5")
+
+	requestTakeback(t, white, black)
+	send(t, black, &protocol.TakebackAnswer{Accept: false})
+	if answer, ok := receive(t, white).(*protocol.TakebackAnswer); !ok || answer.Accept {
+		t.Fatalf("got %+v, want a declined takeback", answer)
+	}
+
+	room.mu.Lock()
+	if len(room.history) != 2 {
+		t.Errorf("declined takeback left %v moves, want 2", len(room.history))
+	}
+	room.mu.Unlock()
+
+	// on their own turn white takes back black's reply too
+	requestTakeback(t, white, black)
+	acceptTakeback(t, white, black, black)
+
+	room.mu.Lock()
+	if fen := room.position.FEN(); fen != chess.StartingFEN {
+		t.Errorf("position after taking back 2 moves = %q, want the starting position", fen)
+	}
+
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if moves := room.clock.Moves(color); moves != 0 {
+			t.Errorf("%s has made %v moves on the clock, want 0", color, moves)
+		}
+
+		if remaining := room.clock.Remaining(color); remaining > 5*time.Minute || remaining < 5*time.Minute-time.Second {
+			t.Errorf("%s has %v left, want about 5m", color, remaining)
+		}
+	}
+	room.mu.Unlock()
+
+	// on black's turn only white's move is taken back
+	playMoves(t, white, black, "d2d4")
+	requestTakeback(t, white, black)
+	acceptTakeback(t, white, black, black)
+
+	room.mu.Lock()
+	if len(room.history) != 0 || room.position.Turn != chess.White {
+		t.Errorf("takeback on the opponent's turn left %v moves with %s to move, want none with white to move", len(room.history), room.position.Turn)
+	}
+	room.mu.Unlock()
+}
+
+func TestMovesCancelTakebackRequests(t *testing.T) {
+	white, black := startGame(t, newTestServer())
+
+	playMoves(t, white, black, "e2e4", "e7e5")
+	requestTakeback(t, white, black)
+	playMoves(t, white, black, "d2d4")
+
+	send(t, black, &protocol.TakebackAnswer{Accept: true})
+	if e, ok := receive(t, black).(*protocol.Error); !ok || e.Code != protocol.ErrRejected {
+		t.Fatalf("got %+v, want the answer to a cancelled takeback rejected", e)
+	}
+}
+
+func TestChat(t *testing.T) {
+	white, black := startGame(t, newTestServer())
+
+	send(t, white, &protocol.Chat{Text: "good luck"})
+	if chat, ok := receive(t, black).(*protocol.Chat); !ok || chat.Text != "good luck" || chat.From == "" {
+		t.Fatalf("got %+v, want chat from white", chat)
+	}
+}
+
+// onlyRoom returns the only room on s
+func onlyRoom(t *testing.T, s *Server) *Room {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.rooms) != 1 {
+		t.Fatalf("server has %v rooms, want 1", len(s.rooms))
+	}
+
+	for _, room := range s.rooms {
+		return room
+	}
+
+	return nil
+}
+
+func TestMovesAreCompensatedForLag(t *testing.T) {
+	s := newTestServer()
+	s.lagCap = 150 * time.Millisecond
+	white, black := startGame(t, s)
+
+	// stop the clock at the start of white's turn and have white lag more than the cap
+	room := onlyRoom(t, s)
+	now := time.Now()
+
+	room.mu.Lock()
+	room.now = func() time.Time { return now }
+	room.turnStart = now
+	room.players[0].mu.Lock()
+	room.players[0].rtt = 400 * time.Millisecond
+	room.players[0].mu.Unlock()
+	room.mu.Unlock()
+
+	now = now.Add(time.Second)
+	send(t, white, &protocol.Move{Move: "e2e4"})
+
+	for _, c := range []*protocol.Conn{white, black} {
+		made, ok := receive(t, c).(*protocol.MoveMade)
+		if !ok {
+			t.Fatal("move was not relayed")
+		}
+
+		// 5 minutes - (1s thinking - 150ms lag) + 3s increment
+		if made.WhiteClock != 302150 || made.BlackClock != 300000 {
+			t.Fatalf("got clocks %v and %v, want 302150 and 300000", made.WhiteClock, made.BlackClock)
+		}
+	}
+}
+
+func TestServerFlagsPlayers(t *testing.T) {
+	s := newTestServer()
+	s.lagCap = 0
+
+	creator := connect(t, s)
+	send(t, creator, &protocol.CreateRoom{TimeControl: "0.002"})
+	created := receive(t, creator).(*protocol.RoomCreated)
+
+	joiner := connect(t, s)
+	send(t, joiner, &protocol.JoinRoom{Code: created.Code})
+	receive(t, creator)
+	receive(t, joiner)
+
+	for _, c := range []*protocol.Conn{creator, joiner} {
+		if sync, ok := receive(t, c).(*protocol.ClockSync); !ok || sync.WhiteClock != 0 {
+			t.Fatalf("got %+v, want white's clock at 0", sync)
+		}
+
+		over, ok := receive(t, c).(*protocol.GameOver)
+		if !ok || over.Reason != "timeout" || over.Loser != "white" {
+			t.Fatalf("got %+v, want white to lose on time", over)
+		}
+	}
+}
+
+func TestClientsArePinged(t *testing.T) {
+	s := newTestServer()
+	s.pingInterval = time.Hour
+
+	client, server := net.Pipe()
+	go s.handle(server)
+	defer client.Close()
+
+	c := protocol.NewConn(client)
+	send(t, c, &protocol.Hello{Version: protocol.Version, Name: "pinged"})
+	receive(t, c)
+
+	if _, ok := receive(t, c).(*protocol.Ping); !ok {
+		t.Fatal("client was not pinged")
+	}
+}
+
+func TestPongMeasuresLag(t *testing.T) {
+	p := &player{pingID: 1, pingSent: time.Now().Add(-40 * time.Millisecond)}
+	p.pong(1)
+
+	if lag := p.lag(); lag < 20*time.Millisecond || lag > 100*time.Millisecond {
+		t.Fatalf("lag is %v, want about 20ms", lag)
+	}
+
+	// a second pong for the same ping is ignored
+	before := p.lag()
+	p.pong(1)
+
+	if p.lag() != before {
+		t.Error("answered ping changed the lag")
+	}
+}